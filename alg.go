@@ -1,12 +1,32 @@
 package jwt
 
 import (
+	"context"
 	"crypto"
 	"crypto/sha256"
 	"crypto/sha512"
+	"errors"
 	"hash"
 )
 
+// Minimum key strengths enforced when signing and verifying. They may be
+// lowered to interoperate with legacy systems.
+var (
+	// MinHMACKeySize is the minimum length of an HMAC secret in bytes. When
+	// zero, secrets must be at least as long as the output of the algorithm's
+	// hash function, as required by RFC 7518.
+	MinHMACKeySize = 0
+
+	// MinRSAKeySize is the minimum size of an RSA modulus in bits
+	MinRSAKeySize = 2048
+)
+
+// Errors relating to key strength
+var (
+	ErrWeakKey      = errors.New("The key is too short for this algorithm")
+	ErrInvalidCurve = errors.New("The key's curve doesn't match the algorithm")
+)
+
 // SigningAlgorithm represents a signing algorithm
 type SigningAlgorithm interface {
 	Name() string
@@ -14,6 +34,104 @@ type SigningAlgorithm interface {
 	Verify(payload string, signature string, key interface{}) error
 }
 
+// ContextSigningAlgorithm is implemented by signing algorithms that can make
+// use of a context, such as those backed by a remote signer. The context is
+// passed through from EncodeContext and ParseContext.
+type ContextSigningAlgorithm interface {
+	SigningAlgorithm
+	SignContext(ctx context.Context, payload string, key interface{}) (string, error)
+	VerifyContext(ctx context.Context, payload string, signature string, key interface{}) error
+}
+
+// ByteSigningAlgorithm is implemented by signing algorithms that can work
+// directly on byte slices, avoiding string conversions. Unlike Sign and
+// Verify the signature is raw rather than base64url encoded. Implementations
+// must not retain payload or signature after returning.
+type ByteSigningAlgorithm interface {
+	SigningAlgorithm
+	SignBytes(payload []byte, key interface{}) ([]byte, error)
+	VerifyBytes(payload []byte, signature []byte, key interface{}) error
+}
+
+// ByteAlgorithm returns alg as a ByteSigningAlgorithm. Algorithms that only
+// implement SigningAlgorithm are wrapped in an adapter that converts to and
+// from strings.
+func ByteAlgorithm(alg SigningAlgorithm) ByteSigningAlgorithm {
+	if byteAlg, ok := alg.(ByteSigningAlgorithm); ok {
+		return byteAlg
+	}
+
+	return byteAdapter{alg}
+}
+
+type byteAdapter struct {
+	SigningAlgorithm
+}
+
+func (a byteAdapter) SignBytes(payload []byte, key interface{}) ([]byte, error) {
+	sig, err := a.Sign(string(payload), key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return decode(sig)
+}
+
+func (a byteAdapter) VerifyBytes(payload []byte, signature []byte, key interface{}) error {
+	return a.Verify(string(payload), encode(signature), key)
+}
+
+// signContext signs the payload using SignContext if the algorithm supports
+// it and SignBytes otherwise. The raw signature is returned.
+func signContext(ctx context.Context, alg SigningAlgorithm, payload []byte, key interface{}) ([]byte, error) {
+	if ctxAlg, ok := alg.(ContextSigningAlgorithm); ok {
+		sig, err := ctxAlg.SignContext(ctx, string(payload), key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return decode(sig)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return ByteAlgorithm(alg).SignBytes(payload, key)
+}
+
+// verifyContext verifies the raw signature using VerifyContext if the
+// algorithm supports it and VerifyBytes otherwise.
+func verifyContext(ctx context.Context, alg SigningAlgorithm, payload []byte, signature []byte, key interface{}) error {
+	if ctxAlg, ok := alg.(ContextSigningAlgorithm); ok {
+		return ctxAlg.VerifyContext(ctx, string(payload), encode(signature), key)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return ByteAlgorithm(alg).VerifyBytes(payload, signature, key)
+}
+
+// PreparedKey is a key that has been parsed and bound to a signing algorithm
+// ahead of time by PrepareKey. Passing a PreparedKey to Sign or Verify skips
+// parsing the key and resolving the hash function on every call. A
+// PreparedKey is safe for concurrent use.
+type PreparedKey struct {
+	hash     crypto.Hash
+	hashFunc func() hash.Hash
+	key      interface{}
+}
+
+// KeyPreparer is implemented by signing algorithms that can prepare keys for
+// repeated use.
+type KeyPreparer interface {
+	PrepareKey(key interface{}) (*PreparedKey, error)
+}
+
 func newHashFunc(method crypto.Hash) (h func() hash.Hash, err error) {
 	switch method {
 	case crypto.SHA256:
@@ -0,0 +1,171 @@
+package jwt
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+)
+
+// The typed claim accessors below look up claims by name. If there is no
+// top-level claim with that exact name, the name is treated as a dotted path
+// into nested objects, so "realm_access.roles" finds the roles member of the
+// realm_access claim. Each returns false if the claim is missing or of the
+// wrong type.
+
+// ClaimString returns the claim as a string
+func (t *token) ClaimString(claim string) (v string, ok bool) {
+	v, ok = t.lookup(claim).(string)
+	return
+}
+
+// ClaimStrings returns the claim as a slice of strings. The claim may be
+// either a single string or an array of strings, as allowed for "aud".
+func (t *token) ClaimStrings(claim string) (v []string, ok bool) {
+	switch c := t.lookup(claim).(type) {
+	case string:
+		return []string{c}, true
+	case []string:
+		return append([]string(nil), c...), true
+	case []interface{}:
+		v = make([]string, len(c))
+		for i, elem := range c {
+			if v[i], ok = elem.(string); !ok {
+				return nil, false
+			}
+		}
+
+		return v, true
+	}
+
+	return nil, false
+}
+
+// ClaimBool returns the claim as a bool
+func (t *token) ClaimBool(claim string) (v bool, ok bool) {
+	v, ok = t.lookup(claim).(bool)
+	return
+}
+
+// ClaimMap returns the claim, which must be a JSON object, as a map
+func (t *token) ClaimMap(claim string) (v map[string]interface{}, ok bool) {
+	v, ok = t.lookup(claim).(map[string]interface{})
+	return
+}
+
+// ClaimInt64 returns the claim as an int64. ok is false if the claim isn't an
+// integer that fits in an int64. Integers above 2^53 only survive parsing
+// exactly if the Parser decodes numbers as json.Number.
+func (t *token) ClaimInt64(claim string) (v int64, ok bool) {
+	return toInt64(t.lookup(claim))
+}
+
+// ClaimTime returns the claim, which must be a NumericDate (the number of
+// seconds since the epoch), as a time.Time.
+func (t *token) ClaimTime(claim string) (v time.Time, ok bool) {
+	return toTime(t.lookup(claim))
+}
+
+func (t *token) lookup(claim string) interface{} {
+	v, _ := lookupClaim(t.claims, claim)
+	return v
+}
+
+// lookupClaim finds the claim with the given name, or failing that follows
+// the name as a dotted path through nested objects
+func lookupClaim(claims map[string]interface{}, name string) (interface{}, bool) {
+	if v, ok := claims[name]; ok {
+		return v, true
+	}
+
+	for {
+		i := strings.IndexByte(name, '.')
+		if i < 0 {
+			v, ok := claims[name]
+			return v, ok
+		}
+
+		nested, ok := claims[name[:i]].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		claims, name = nested, name[i+1:]
+	}
+}
+
+// toInt64 converts a number decoded from JSON, or set with SetClaim, to an
+// int64
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return i, true
+		}
+
+		// may be an integer written in exponent form such as 1.3e9
+		f, err := n.Float64()
+
+		if err != nil {
+			return 0, false
+		}
+
+		return floatToInt64(f)
+	case float64:
+		return floatToInt64(n)
+	case float32:
+		return floatToInt64(float64(n))
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint32:
+		return int64(n), true
+	}
+
+	return 0, false
+}
+
+func floatToInt64(f float64) (int64, bool) {
+	// -2^63 is exactly representable but 2^63-1 isn't, so the upper bound is
+	// exclusive
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, false
+	}
+
+	return int64(f), true
+}
+
+// toTime converts a NumericDate, which may have a fractional part, to a
+// time.Time
+func toTime(v interface{}) (time.Time, bool) {
+	if i, ok := toInt64(v); ok {
+		return time.Unix(i, 0), true
+	}
+
+	var f float64
+
+	switch n := v.(type) {
+	case json.Number:
+		var err error
+		if f, err = n.Float64(); err != nil {
+			return time.Time{}, false
+		}
+	case float64:
+		f = n
+	case float32:
+		f = float64(n)
+	default:
+		return time.Time{}, false
+	}
+
+	if math.IsNaN(f) || math.IsInf(f, 0) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return time.Time{}, false
+	}
+
+	sec, frac := math.Modf(f)
+
+	return time.Unix(int64(sec), int64(frac*1e9)), true
+}
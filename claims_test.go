@@ -0,0 +1,155 @@
+package jwt
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestClaimInt64(t *testing.T) {
+	tokenString := signToken(t, `{"alg":"HS256","typ":"JWT"}`, `{"uid":9007199254740993,"neg":-42,"exp":4.1e9,"frac":1.5,"str":"1"}`)
+
+	parsed, err := NewParser().Parse(tokenString, HMAC, testKey)
+
+	if err != nil {
+		t.Fatalf("Error occured parsing the token: %v", err)
+	}
+
+	tok := parsed.(TypedClaimsToken)
+
+	if _, ok := tok.Claim("uid").(json.Number); !ok {
+		t.Errorf("NewParser did not decode numbers as json.Number")
+	}
+
+	for _, test := range []struct {
+		claim string
+		value int64
+		ok    bool
+	}{
+		{"uid", 9007199254740993, true},
+		{"neg", -42, true},
+		{"exp", 4100000000, true},
+		{"frac", 0, false},
+		{"str", 0, false},
+		{"missing", 0, false},
+	} {
+		if v, ok := tok.ClaimInt64(test.claim); v != test.value || ok != test.ok {
+			t.Errorf("ClaimInt64(%q), expected %v, %v but got %v, %v", test.claim, test.value, test.ok, v, ok)
+		}
+	}
+
+	// float64 loses precision
+	parsed, _ = ParseToken(tokenString, HMAC, testKey)
+
+	if v, _ := parsed.(TypedClaimsToken).ClaimInt64("uid"); v != 9007199254740992 {
+		t.Errorf("Expected float64 decoding to round uid but got %v", v)
+	}
+}
+
+func TestClaimTime(t *testing.T) {
+	tok := NewToken(HMAC).(TypedClaimsToken)
+	tok.SetClaim("iat", 1300819380)
+	tok.SetClaim("frac", 1300819380.5)
+	tok.SetClaim("num", json.Number("1300819380"))
+	tok.SetClaim("str", "1300819380")
+
+	for _, test := range []struct {
+		claim string
+		value time.Time
+		ok    bool
+	}{
+		{"iat", time.Unix(1300819380, 0), true},
+		{"frac", time.Unix(1300819380, 5e8), true},
+		{"num", time.Unix(1300819380, 0), true},
+		{"str", time.Time{}, false},
+		{"missing", time.Time{}, false},
+	} {
+		if v, ok := tok.ClaimTime(test.claim); !v.Equal(test.value) || ok != test.ok {
+			t.Errorf("ClaimTime(%q), expected %v, %v but got %v, %v", test.claim, test.value, test.ok, v, ok)
+		}
+	}
+}
+
+func TestParserJSONNumberExpired(t *testing.T) {
+	for _, useNumber := range []bool{true, false} {
+		p := NewParser(WithJSONNumber(useNumber))
+
+		expired := signToken(t, `{"alg":"HS256"}`, `{"exp":1300819380}`)
+		if _, err := p.Parse(expired, HMAC, testKey); err != ExpiredError {
+			t.Errorf("[json.Number=%v] Expected ExpiredError but got %v", useNumber, err)
+		}
+
+		notBefore := signToken(t, `{"alg":"HS256"}`, `{"nbf":4.1e9}`)
+		if _, err := p.Parse(notBefore, HMAC, testKey); err != NotYetValidError {
+			t.Errorf("[json.Number=%v] Expected NotYetValidError but got %v", useNumber, err)
+		}
+	}
+}
+
+func TestTypedClaims(t *testing.T) {
+	tokenString := signToken(t, `{"alg":"HS256","typ":"JWT"}`, `{
+		"sub": "user",
+		"aud": "api",
+		"admin": true,
+		"a.b": "literal",
+		"a": {"b": "nested"},
+		"realm_access": {"roles": ["offline_access", "admin"]},
+		"resource_access": {"account": {"roles": ["view-profile"], "count": 2}},
+		"mixed": ["a", 1]
+	}`)
+
+	parsed, err := NewParser().Parse(tokenString, HMAC, testKey)
+
+	if err != nil {
+		t.Fatalf("Error occured parsing the token: %v", err)
+	}
+
+	tok := parsed.(TypedClaimsToken)
+
+	if v, ok := tok.ClaimString("sub"); v != "user" || !ok {
+		t.Errorf("ClaimString(sub), got %v, %v", v, ok)
+	}
+
+	if _, ok := tok.ClaimString("admin"); ok {
+		t.Errorf("ClaimString returned a bool claim")
+	}
+
+	if v, ok := tok.ClaimBool("admin"); !v || !ok {
+		t.Errorf("ClaimBool(admin), got %v, %v", v, ok)
+	}
+
+	if v, ok := tok.ClaimStrings("aud"); len(v) != 1 || v[0] != "api" || !ok {
+		t.Errorf("ClaimStrings(aud), got %v, %v", v, ok)
+	}
+
+	if v, ok := tok.ClaimStrings("realm_access.roles"); len(v) != 2 || v[1] != "admin" || !ok {
+		t.Errorf("ClaimStrings(realm_access.roles), got %v, %v", v, ok)
+	}
+
+	if v, ok := tok.ClaimStrings("resource_access.account.roles"); len(v) != 1 || v[0] != "view-profile" || !ok {
+		t.Errorf("ClaimStrings(resource_access.account.roles), got %v, %v", v, ok)
+	}
+
+	if v, ok := tok.ClaimInt64("resource_access.account.count"); v != 2 || !ok {
+		t.Errorf("ClaimInt64(resource_access.account.count), got %v, %v", v, ok)
+	}
+
+	if _, ok := tok.ClaimStrings("mixed"); ok {
+		t.Errorf("ClaimStrings returned an array containing a number")
+	}
+
+	if v, ok := tok.ClaimMap("realm_access"); len(v) != 1 || !ok {
+		t.Errorf("ClaimMap(realm_access), got %v, %v", v, ok)
+	}
+
+	// exact names take precedence over paths
+	if v, _ := tok.ClaimString("a.b"); v != "literal" {
+		t.Errorf("ClaimString(a.b), expected the literal claim but got %v", v)
+	}
+
+	for _, missing := range []string{"missing", "realm_access.missing", "sub.missing", "realm_access.roles.0", "."} {
+		if v, ok := tok.ClaimStrings(missing); ok {
+			t.Errorf("ClaimStrings(%q), expected a missing claim but got %v", missing, v)
+		}
+	}
+}
@@ -0,0 +1,401 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"hash"
+	"math/big"
+)
+
+// SigningAlgorithmECDSA represents an ECDSA signing algorithm
+type SigningAlgorithmECDSA struct {
+	name  string
+	hash  crypto.Hash
+	curve elliptic.Curve
+}
+
+// Instances of supported ECDSA algorithms, each bound to its curve
+var (
+	ES256 = &SigningAlgorithmECDSA{"ES256", crypto.SHA256, elliptic.P256()}
+	ES384 = &SigningAlgorithmECDSA{"ES384", crypto.SHA384, elliptic.P384()}
+	ES512 = &SigningAlgorithmECDSA{"ES512", crypto.SHA512, elliptic.P521()}
+)
+
+// ECPrivateKey is a private key for use with the ECDSA algorithms. It can
+// also be used to verify signatures.
+type ECPrivateKey struct {
+	Key        *ecdsa.PrivateKey
+	Algorithms []string
+}
+
+// ECPublicKey is a public key for use with the ECDSA algorithms
+type ECPublicKey struct {
+	Key        *ecdsa.PublicKey
+	Algorithms []string
+}
+
+// Name returns the name of the algorithm as specified in JSON Web Algorithms
+func (alg *SigningAlgorithmECDSA) Name() string {
+	return alg.name
+}
+
+// keySize returns the size in bytes of each of the two integers that make up
+// a signature
+func (alg *SigningAlgorithmECDSA) keySize() int {
+	return (alg.curve.Params().BitSize + 7) / 8
+}
+
+// ecKeyPair holds the parsed keys of a PreparedKey. private is nil if the key
+// was prepared from a public key.
+type ecKeyPair struct {
+	private *ecdsa.PrivateKey
+	public  *ecdsa.PublicKey
+}
+
+// PrepareKey parses the key once so that it can be reused across calls to
+// Sign and Verify. The key may be an *ecdsa.PrivateKey, an *ecdsa.PublicKey,
+// either of them wrapped in an ECPrivateKey or ECPublicKey, or a string or
+// byte array containing either of them PEM encoded.
+func (alg *SigningAlgorithmECDSA) PrepareKey(key interface{}) (*PreparedKey, error) {
+	var (
+		keys = &ecKeyPair{}
+		err  error
+	)
+
+	switch k := key.(type) {
+	case string:
+		keys, err = parseECKeyPairFromPEM([]byte(k))
+	case []byte:
+		keys, err = parseECKeyPairFromPEM(k)
+	case *ecdsa.PrivateKey:
+		if k == nil {
+			return nil, ErrInvalidKey
+		}
+
+		keys.private = k
+		keys.public = &k.PublicKey
+	case *ecdsa.PublicKey:
+		keys.public = k
+	case ECPrivateKey:
+		if !allowsAlgorithm(k.Algorithms, alg) {
+			return nil, ErrAlgorithmMismatch
+		}
+
+		if k.Key == nil {
+			return nil, ErrInvalidKey
+		}
+
+		keys.private = k.Key
+		keys.public = &k.Key.PublicKey
+	case ECPublicKey:
+		if !allowsAlgorithm(k.Algorithms, alg) {
+			return nil, ErrAlgorithmMismatch
+		}
+
+		keys.public = k.Key
+	default:
+		return nil, invalidKey(key)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if keys.public == nil {
+		return nil, ErrInvalidKey
+	}
+
+	if keys.public.Curve != alg.curve {
+		return nil, ErrInvalidCurve
+	}
+
+	hashFunc, err := newHashFunc(alg.hash)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &PreparedKey{hash: alg.hash, hashFunc: hashFunc, key: keys}, nil
+}
+
+// prepared returns the key pair held by a PreparedKey if it was prepared for
+// this algorithm
+func (alg *SigningAlgorithmECDSA) prepared(pk *PreparedKey) (*ecKeyPair, error) {
+	if keys, ok := pk.key.(*ecKeyPair); ok && pk.hash == alg.hash && keys.public.Curve == alg.curve {
+		return keys, nil
+	}
+
+	return nil, ErrInvalidKey
+}
+
+func (alg *SigningAlgorithmECDSA) sign(payload []byte, key interface{}) ([]byte, error) {
+	var (
+		ecKey    *ecdsa.PrivateKey
+		hashFunc func() hash.Hash
+		err      error
+	)
+
+	switch k := key.(type) {
+	case string:
+		if ecKey, err = ParseECPrivateKeyFromPEM([]byte(k)); err != nil {
+			return nil, err
+		}
+	case []byte:
+		if ecKey, err = ParseECPrivateKeyFromPEM(k); err != nil {
+			return nil, err
+		}
+	case *ecdsa.PrivateKey:
+		ecKey = k
+	case ECPrivateKey:
+		if !allowsAlgorithm(k.Algorithms, alg) {
+			return nil, ErrAlgorithmMismatch
+		}
+
+		ecKey = k.Key
+	case *PreparedKey:
+		var keys *ecKeyPair
+		if keys, err = alg.prepared(k); err != nil {
+			return nil, err
+		}
+
+		if keys.private == nil {
+			return nil, ErrNotECPrivateKey
+		}
+
+		ecKey = keys.private
+		hashFunc = k.hashFunc
+	default:
+		return nil, invalidKey(key)
+	}
+
+	if ecKey == nil {
+		return nil, ErrInvalidKey
+	}
+
+	if ecKey.Curve != alg.curve {
+		return nil, ErrInvalidCurve
+	}
+
+	if hashFunc == nil {
+		if hashFunc, err = newHashFunc(alg.hash); err != nil {
+			return nil, err
+		}
+	}
+
+	hasher := hashFunc()
+	hasher.Write(payload)
+
+	r, s, err := ecdsa.Sign(rand.Reader, ecKey, hasher.Sum(nil))
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the signature is the concatenation of r and s, each padded to the size
+	// of the key
+	size := (ecKey.Curve.Params().BitSize + 7) / 8
+	sig := make([]byte, 2*size)
+	r.FillBytes(sig[:size])
+	s.FillBytes(sig[size:])
+
+	return sig, nil
+}
+
+// Sign takes a string payload and a key as either an ecdsa.PrivateKey, an
+// ECPrivateKey, a PreparedKey or a string or byte array containing a PEM
+// encoded key. The key must be on the curve required by the algorithm.
+// Either returns the signature as a string or an error.
+func (alg *SigningAlgorithmECDSA) Sign(payload string, key interface{}) (string, error) {
+	var (
+		sigBytes []byte
+		err      error
+	)
+
+	if sigBytes, err = alg.sign([]byte(payload), key); err == nil {
+		return encode(sigBytes), nil
+	}
+
+	return "", err
+}
+
+// SignBytes is like Sign but works on byte slices and returns the raw
+// signature
+func (alg *SigningAlgorithmECDSA) SignBytes(payload []byte, key interface{}) ([]byte, error) {
+	return alg.sign(payload, key)
+}
+
+// Verify checks that the signature is valid
+func (alg *SigningAlgorithmECDSA) Verify(payload string, signature string, key interface{}) error {
+	var (
+		sigBytes []byte
+		err      error
+	)
+
+	// decode the signature
+	if sigBytes, err = decode(signature); err != nil {
+		return err
+	}
+
+	return alg.VerifyBytes([]byte(payload), sigBytes, key)
+}
+
+// VerifyBytes is like Verify but works on byte slices and takes the raw
+// signature
+func (alg *SigningAlgorithmECDSA) VerifyBytes(payload []byte, signature []byte, key interface{}) error {
+	var (
+		ecKey    *ecdsa.PublicKey
+		hashFunc func() hash.Hash
+		err      error
+	)
+
+	switch k := key.(type) {
+	case string:
+		if ecKey, err = ParseECPublicKeyFromPEM([]byte(k)); err != nil {
+			return err
+		}
+	case []byte:
+		if ecKey, err = ParseECPublicKeyFromPEM(k); err != nil {
+			return err
+		}
+	case *ecdsa.PublicKey:
+		ecKey = k
+	case ECPublicKey:
+		if !allowsAlgorithm(k.Algorithms, alg) {
+			return ErrAlgorithmMismatch
+		}
+
+		ecKey = k.Key
+	case ECPrivateKey:
+		if !allowsAlgorithm(k.Algorithms, alg) {
+			return ErrAlgorithmMismatch
+		}
+
+		if k.Key == nil {
+			return ErrInvalidKey
+		}
+
+		ecKey = &k.Key.PublicKey
+	case *PreparedKey:
+		var keys *ecKeyPair
+		if keys, err = alg.prepared(k); err != nil {
+			return err
+		}
+
+		ecKey = keys.public
+		hashFunc = k.hashFunc
+	default:
+		return invalidKey(key)
+	}
+
+	if ecKey == nil {
+		return ErrInvalidKey
+	}
+
+	if ecKey.Curve != alg.curve {
+		return ErrInvalidCurve
+	}
+
+	size := alg.keySize()
+	if len(signature) != 2*size {
+		return ErrBadSignature
+	}
+
+	if hashFunc == nil {
+		if hashFunc, err = newHashFunc(alg.hash); err != nil {
+			return err
+		}
+	}
+
+	hasher := hashFunc()
+	hasher.Write(payload)
+
+	r := new(big.Int).SetBytes(signature[:size])
+	s := new(big.Int).SetBytes(signature[size:])
+
+	if ecdsa.Verify(ecKey, hasher.Sum(nil), r, s) {
+		return nil
+	}
+
+	return ErrBadSignature
+}
+
+// Errors relating to parsing EC keys
+var (
+	ErrNotECPrivateKey = errors.New("Key is not a valid ECDSA private key")
+	ErrNotECPublicKey  = errors.New("Key is not a valid ECDSA public key")
+)
+
+// ParseECPrivateKeyFromPEM decodes a PEM encoded SEC1 or PKCS8 private key
+func ParseECPrivateKeyFromPEM(key []byte) (*ecdsa.PrivateKey, error) {
+	var err error
+
+	// Parse PEM block
+	var block *pem.Block
+	if block, _ = pem.Decode(key); block == nil {
+		return nil, ErrKeyMustBePEMEncoded
+	}
+
+	var parsedKey interface{}
+	if parsedKey, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+		if parsedKey, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+			return nil, err
+		}
+	}
+
+	pkey, ok := parsedKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, ErrNotECPrivateKey
+	}
+
+	return pkey, nil
+}
+
+// ParseECPublicKeyFromPEM decodes a PEM encoded PKIX public key or
+// certificate
+func ParseECPublicKeyFromPEM(key []byte) (*ecdsa.PublicKey, error) {
+	var err error
+
+	// Parse PEM block
+	var block *pem.Block
+	if block, _ = pem.Decode(key); block == nil {
+		return nil, ErrKeyMustBePEMEncoded
+	}
+
+	// Parse the key
+	var parsedKey interface{}
+	if parsedKey, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			parsedKey = cert.PublicKey
+		} else {
+			return nil, err
+		}
+	}
+
+	pkey, ok := parsedKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, ErrNotECPublicKey
+	}
+
+	return pkey, nil
+}
+
+// parseECKeyPairFromPEM decodes a PEM encoded private key, falling back to a
+// public key if the PEM doesn't hold a private key
+func parseECKeyPairFromPEM(key []byte) (*ecKeyPair, error) {
+	if private, err := ParseECPrivateKeyFromPEM(key); err == nil {
+		return &ecKeyPair{private: private, public: &private.PublicKey}, nil
+	}
+
+	public, err := ParseECPublicKeyFromPEM(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ecKeyPair{public: public}, nil
+}
@@ -0,0 +1,133 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// from RFC 7515 appendix A.3
+var (
+	es256Test = "eyJhbGciOiJFUzI1NiJ9." +
+		"eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ." +
+		"DtEhU3ljbEg8L38VWAfUAqOyKAM6-Xx-F4GawxaepmXFCgfTjDxw5djxLa8ISlSApmWQxfKTUJqPP3-Kg6NU1Q"
+
+	es256X = "f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU"
+	es256Y = "x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0"
+)
+
+func TestES256Verify(t *testing.T) {
+	x, _ := decode(es256X)
+	y, _ := decode(es256Y)
+
+	key := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}
+
+	segments := strings.Split(es256Test, ".")
+
+	if err := ES256.Verify(strings.Join(segments[0:2], "."), segments[2], key); err != nil {
+		t.Errorf("Error while verifying signature: %v", err)
+	}
+
+	if err := ES256.Verify(strings.Join(segments[0:2], ".")+"x", segments[2], key); err != ErrBadSignature {
+		t.Errorf("Expected ErrBadSignature but got %v", err)
+	}
+}
+
+func TestECDSASignVerify(t *testing.T) {
+	for _, alg := range []*SigningAlgorithmECDSA{ES256, ES384, ES512} {
+		private, err := ecdsa.GenerateKey(alg.curve, rand.Reader)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		der, _ := x509.MarshalPKIXPublicKey(&private.PublicKey)
+		publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+		der, _ = x509.MarshalECPrivateKey(private)
+		privatePEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+
+		prepared, err := alg.PrepareKey(privatePEM)
+
+		if err != nil {
+			t.Fatalf("[%v] Error while preparing key: %v", alg.Name(), err)
+		}
+
+		for _, key := range []interface{}{private, privatePEM, string(privatePEM), ECPrivateKey{Key: private}, prepared} {
+			tok := NewToken(alg)
+			tok.SetClaim("foo", "bar")
+
+			encoded, err := tok.Encode(key)
+
+			if err != nil {
+				t.Errorf("[%v] Error while signing with %T: %v", alg.Name(), key, err)
+				continue
+			}
+
+			for _, verifyKey := range []interface{}{&private.PublicKey, publicPEM, ECPublicKey{Key: &private.PublicKey}, prepared} {
+				if _, err := ParseToken(encoded, alg, verifyKey); err != nil {
+					t.Errorf("[%v] Error while verifying with %T: %v", alg.Name(), verifyKey, err)
+				}
+			}
+		}
+	}
+}
+
+func TestECDSAKeyMismatch(t *testing.T) {
+	private, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	prepared, _ := ES384.PrepareKey(private)
+
+	if _, err := ES256.Sign("payload", prepared); err != ErrInvalidKey {
+		t.Errorf("Expected ErrInvalidKey using a key prepared for ES384 but got %v", err)
+	}
+
+	if _, err := ES256.Sign("payload", RSAPrivateKey{}); err != ErrAlgorithmMismatch {
+		t.Errorf("Expected ErrAlgorithmMismatch signing with an RSAPrivateKey but got %v", err)
+	}
+}
+
+func TestECDSACurve(t *testing.T) {
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	p224, _ := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+
+	for _, key := range []*ecdsa.PrivateKey{p384, p224} {
+		if _, err := ES256.Sign("payload", key); err != ErrInvalidCurve {
+			t.Errorf("Expected ErrInvalidCurve signing with %v but got %v", key.Curve.Params().Name, err)
+		}
+
+		if err := ES256.Verify("payload", "", &key.PublicKey); err != ErrInvalidCurve {
+			t.Errorf("Expected ErrInvalidCurve verifying with %v but got %v", key.Curve.Params().Name, err)
+		}
+
+		if _, err := ES256.PrepareKey(key); err != ErrInvalidCurve {
+			t.Errorf("Expected ErrInvalidCurve preparing %v but got %v", key.Curve.Params().Name, err)
+		}
+	}
+}
+
+func TestECDSANilKeys(t *testing.T) {
+	for _, key := range []interface{}{ECPrivateKey{}, ECPublicKey{}, (*ecdsa.PrivateKey)(nil), (*ecdsa.PublicKey)(nil)} {
+		if err := ES256.VerifyBytes([]byte("payload"), []byte("signature"), key); err != ErrInvalidKey {
+			t.Errorf("Verifying with %#v, expected ErrInvalidKey but got %v", key, err)
+		}
+
+		if _, err := ES256.PrepareKey(key); err != ErrInvalidKey {
+			t.Errorf("Preparing %#v, expected ErrInvalidKey but got %v", key, err)
+		}
+	}
+
+	for _, key := range []interface{}{ECPrivateKey{}, (*ecdsa.PrivateKey)(nil)} {
+		if _, err := ES256.SignBytes([]byte("payload"), key); err != ErrInvalidKey {
+			t.Errorf("Signing with %#v, expected ErrInvalidKey but got %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"errors"
+)
+
+// SigningAlgorithmEdDSA represents the EdDSA signing algorithm using Ed25519
+// as described in RFC 8037
+type SigningAlgorithmEdDSA struct{}
+
+// EdDSA signs with Ed25519 keys
+var EdDSA = &SigningAlgorithmEdDSA{}
+
+// Errors relating to Ed25519 keys
+var (
+	ErrNotEdPrivateKey = errors.New("Key is not a valid Ed25519 private key")
+	ErrNotEdPublicKey  = errors.New("Key is not a valid Ed25519 public key")
+)
+
+// Ed25519PrivateKey is a private key for use with the EdDSA algorithm. It can
+// also be used to verify signatures.
+type Ed25519PrivateKey struct {
+	Key        ed25519.PrivateKey
+	Algorithms []string
+}
+
+// Ed25519PublicKey is a public key for use with the EdDSA algorithm
+type Ed25519PublicKey struct {
+	Key        ed25519.PublicKey
+	Algorithms []string
+}
+
+// Name returns the name of the algorithm as specified in JSON Web Algorithms
+func (alg *SigningAlgorithmEdDSA) Name() string {
+	return "EdDSA"
+}
+
+// edKeyPair holds the parsed keys of a PreparedKey. private is nil if the key
+// was prepared from a public key.
+type edKeyPair struct {
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+// PrepareKey parses the key once so that it can be reused across calls to
+// Sign and Verify. The key may be an ed25519.PrivateKey, an
+// ed25519.PublicKey, either of them wrapped in an Ed25519PrivateKey or
+// Ed25519PublicKey, or a string or byte array containing either of them in a
+// format accepted by ParsePrivateKey or ParsePublicKey.
+func (alg *SigningAlgorithmEdDSA) PrepareKey(key interface{}) (*PreparedKey, error) {
+	keys := &edKeyPair{}
+
+	switch k := key.(type) {
+	case string:
+		keys = parseEdKeyPair([]byte(k))
+	case []byte:
+		keys = parseEdKeyPair(k)
+	case ed25519.PrivateKey:
+		if len(k) != ed25519.PrivateKeySize {
+			return nil, ErrNotEdPrivateKey
+		}
+
+		keys.private = k
+	case ed25519.PublicKey:
+		keys.public = k
+	case Ed25519PrivateKey:
+		if !allowsAlgorithm(k.Algorithms, alg) {
+			return nil, ErrAlgorithmMismatch
+		}
+
+		if len(k.Key) != ed25519.PrivateKeySize {
+			return nil, ErrNotEdPrivateKey
+		}
+
+		keys.private = k.Key
+	case Ed25519PublicKey:
+		if !allowsAlgorithm(k.Algorithms, alg) {
+			return nil, ErrAlgorithmMismatch
+		}
+
+		keys.public = k.Key
+	default:
+		return nil, invalidKey(key)
+	}
+
+	if keys.private != nil {
+		keys.public = keys.private.Public().(ed25519.PublicKey)
+	}
+
+	if len(keys.public) != ed25519.PublicKeySize {
+		return nil, ErrNotEdPublicKey
+	}
+
+	return &PreparedKey{key: keys}, nil
+}
+
+// prepared returns the key pair held by a PreparedKey if it was prepared for
+// this algorithm
+func (alg *SigningAlgorithmEdDSA) prepared(pk *PreparedKey) (*edKeyPair, error) {
+	if keys, ok := pk.key.(*edKeyPair); ok {
+		return keys, nil
+	}
+
+	return nil, ErrInvalidKey
+}
+
+// parseEdKeyPair parses a private or public key, leaving the pair empty if
+// it isn't an Ed25519 key
+func parseEdKeyPair(key []byte) *edKeyPair {
+	if private, err := ParsePrivateKey(key); err == nil {
+		if k, ok := private.(ed25519.PrivateKey); ok && len(k) == ed25519.PrivateKeySize {
+			return &edKeyPair{private: k}
+		}
+
+		return &edKeyPair{}
+	}
+
+	public, _ := ParsePublicKey(key)
+	k, _ := public.(ed25519.PublicKey)
+
+	return &edKeyPair{public: k}
+}
+
+func (alg *SigningAlgorithmEdDSA) sign(payload []byte, key interface{}) ([]byte, error) {
+	var edKey ed25519.PrivateKey
+
+	switch k := key.(type) {
+	case string:
+		parsed, err := ParsePrivateKey([]byte(k))
+
+		if err != nil {
+			return nil, err
+		}
+
+		edKey, _ = parsed.(ed25519.PrivateKey)
+	case []byte:
+		parsed, err := ParsePrivateKey(k)
+
+		if err != nil {
+			return nil, err
+		}
+
+		edKey, _ = parsed.(ed25519.PrivateKey)
+	case ed25519.PrivateKey:
+		edKey = k
+	case Ed25519PrivateKey:
+		if !allowsAlgorithm(k.Algorithms, alg) {
+			return nil, ErrAlgorithmMismatch
+		}
+
+		edKey = k.Key
+	case *PreparedKey:
+		keys, err := alg.prepared(k)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if keys.private == nil {
+			return nil, ErrNotEdPrivateKey
+		}
+
+		edKey = keys.private
+	default:
+		return nil, invalidKey(key)
+	}
+
+	if len(edKey) != ed25519.PrivateKeySize {
+		return nil, ErrNotEdPrivateKey
+	}
+
+	return ed25519.Sign(edKey, payload), nil
+}
+
+// Sign takes a string payload and a key as either an ed25519.PrivateKey, an
+// Ed25519PrivateKey, a PreparedKey or a string or byte array containing a
+// PEM encoded key.
+// Either returns the signature as a string or an error.
+func (alg *SigningAlgorithmEdDSA) Sign(payload string, key interface{}) (string, error) {
+	var (
+		sigBytes []byte
+		err      error
+	)
+
+	if sigBytes, err = alg.sign([]byte(payload), key); err == nil {
+		return encode(sigBytes), nil
+	}
+
+	return "", err
+}
+
+// SignBytes is like Sign but works on byte slices and returns the raw
+// signature
+func (alg *SigningAlgorithmEdDSA) SignBytes(payload []byte, key interface{}) ([]byte, error) {
+	return alg.sign(payload, key)
+}
+
+// Verify checks that the signature is valid
+func (alg *SigningAlgorithmEdDSA) Verify(payload string, signature string, key interface{}) error {
+	var (
+		sigBytes []byte
+		err      error
+	)
+
+	// decode the signature
+	if sigBytes, err = decode(signature); err != nil {
+		return err
+	}
+
+	return alg.VerifyBytes([]byte(payload), sigBytes, key)
+}
+
+// VerifyBytes is like Verify but works on byte slices and takes the raw
+// signature
+func (alg *SigningAlgorithmEdDSA) VerifyBytes(payload []byte, signature []byte, key interface{}) error {
+	var edKey ed25519.PublicKey
+
+	switch k := key.(type) {
+	case string:
+		parsed, err := ParsePublicKey([]byte(k))
+
+		if err != nil {
+			return err
+		}
+
+		edKey, _ = parsed.(ed25519.PublicKey)
+	case []byte:
+		parsed, err := ParsePublicKey(k)
+
+		if err != nil {
+			return err
+		}
+
+		edKey, _ = parsed.(ed25519.PublicKey)
+	case ed25519.PublicKey:
+		edKey = k
+	case ed25519.PrivateKey:
+		if len(k) == ed25519.PrivateKeySize {
+			edKey = k.Public().(ed25519.PublicKey)
+		}
+	case Ed25519PublicKey:
+		if !allowsAlgorithm(k.Algorithms, alg) {
+			return ErrAlgorithmMismatch
+		}
+
+		edKey = k.Key
+	case Ed25519PrivateKey:
+		if !allowsAlgorithm(k.Algorithms, alg) {
+			return ErrAlgorithmMismatch
+		}
+
+		if len(k.Key) == ed25519.PrivateKeySize {
+			edKey = k.Key.Public().(ed25519.PublicKey)
+		}
+	case *PreparedKey:
+		keys, err := alg.prepared(k)
+
+		if err != nil {
+			return err
+		}
+
+		edKey = keys.public
+	default:
+		return invalidKey(key)
+	}
+
+	if len(edKey) != ed25519.PublicKeySize {
+		return ErrNotEdPublicKey
+	}
+
+	if ed25519.Verify(edKey, payload, signature) {
+		return nil
+	}
+
+	return ErrBadSignature
+}
@@ -0,0 +1,159 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"strings"
+	"testing"
+)
+
+// from RFC 8037 appendix A
+var (
+	ed25519D   = "nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A"
+	ed25519X   = "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"
+	ed25519JWS = "eyJhbGciOiJFZERTQSJ9.RXhhbXBsZSBvZiBFZDI1NTE5IHNpZ25pbmc." +
+		"hgyY0il_MGCjP0JzlnLWG1PPOt7-09PGcvMg3AIbQR6dWbhijcNR4ki4iylGjg5BhVsPt9g7sVvpAr_MuM0KAg"
+)
+
+func TestEdDSASignVerify(t *testing.T) {
+	seed, _ := decode(ed25519D)
+	private := ed25519.NewKeyFromSeed(seed)
+
+	x, _ := decode(ed25519X)
+	public := ed25519.PublicKey(x)
+
+	segments := strings.Split(ed25519JWS, ".")
+	payload := strings.Join(segments[0:2], ".")
+
+	// Ed25519 signatures are deterministic
+	if sig, err := EdDSA.Sign(payload, private); err != nil || sig != segments[2] {
+		t.Errorf("Expected signature %v but got %v (%v)", segments[2], sig, err)
+	}
+
+	for _, key := range []interface{}{public, private} {
+		if err := EdDSA.Verify(payload, segments[2], key); err != nil {
+			t.Errorf("Error while verifying with %T: %v", key, err)
+		}
+	}
+
+	if err := EdDSA.Verify(payload+"x", segments[2], public); err != ErrBadSignature {
+		t.Errorf("Expected ErrBadSignature but got %v", err)
+	}
+
+	if _, err := EdDSA.Sign(payload, public); err != ErrInvalidKey {
+		t.Errorf("Expected ErrInvalidKey signing with a public key but got %v", err)
+	}
+
+	if err := EdDSA.Verify(payload, segments[2], ed25519.PublicKey(x[:16])); err != ErrNotEdPublicKey {
+		t.Errorf("Expected ErrNotEdPublicKey but got %v", err)
+	}
+}
+
+func TestEdDSAToken(t *testing.T) {
+	public, private, _ := ed25519.GenerateKey(nil)
+
+	tok := NewToken(EdDSA)
+	tok.SetClaim("foo", "bar")
+
+	encoded, err := tok.Encode(private)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ParseToken(encoded, EdDSA, public); err != nil {
+		t.Errorf("Error while verifying: %v", err)
+	}
+
+	// PEM and OpenSSH encoded keys are parsed
+	if _, err := EdDSA.Sign("payload", sshEd25519Key); err != nil {
+		t.Errorf("Error while signing with an OpenSSH key: %v", err)
+	}
+
+	if _, err := ParseToken(encoded, EdDSA, sshEd25519PublicKey); err != BadSignatureError {
+		t.Errorf("Expected BadSignatureError with another key but got %v", err)
+	}
+}
+
+func TestEdDSAKeyTypes(t *testing.T) {
+	seed, _ := decode(ed25519D)
+	private := ed25519.NewKeyFromSeed(seed)
+	public := private.Public().(ed25519.PublicKey)
+
+	segments := strings.Split(ed25519JWS, ".")
+	payload := strings.Join(segments[0:2], ".")
+
+	prepared, err := EdDSA.PrepareKey(private)
+
+	if err != nil {
+		t.Fatalf("Error while preparing key: %v", err)
+	}
+
+	for _, key := range []interface{}{
+		Ed25519PrivateKey{Key: private},
+		Ed25519PrivateKey{Key: private, Algorithms: []string{"EdDSA"}},
+		prepared,
+	} {
+		if sig, err := EdDSA.Sign(payload, key); err != nil || sig != segments[2] {
+			t.Errorf("Signing with %T, expected signature %v but got %v (%v)", key, segments[2], sig, err)
+		}
+	}
+
+	preparedPublic, err := EdDSA.PrepareKey(sshEd25519PublicKey)
+
+	if err != nil {
+		t.Fatalf("Error while preparing an OpenSSH key: %v", err)
+	}
+
+	if _, err := EdDSA.Sign(payload, preparedPublic); err != ErrNotEdPrivateKey {
+		t.Errorf("Expected ErrNotEdPrivateKey signing with a prepared public key but got %v", err)
+	}
+
+	for _, key := range []interface{}{
+		Ed25519PublicKey{Key: public},
+		Ed25519PrivateKey{Key: private},
+		prepared,
+	} {
+		if err := EdDSA.Verify(payload, segments[2], key); err != nil {
+			t.Errorf("Error while verifying with %T: %v", key, err)
+		}
+	}
+
+	// keys bound to other algorithms or families are refused
+	for _, key := range []interface{}{
+		Ed25519PrivateKey{Key: private, Algorithms: []string{"ES256"}},
+		Ed25519PublicKey{Key: public, Algorithms: []string{"ES256"}},
+	} {
+		if err := EdDSA.Verify(payload, segments[2], key); err != ErrAlgorithmMismatch {
+			t.Errorf("Expected ErrAlgorithmMismatch verifying with %#v but got %v", key, err)
+		}
+
+		if _, err := EdDSA.PrepareKey(key); err != ErrAlgorithmMismatch {
+			t.Errorf("Expected ErrAlgorithmMismatch preparing %#v but got %v", key, err)
+		}
+
+		if err := ES256.Verify(payload, segments[2], key); err != ErrAlgorithmMismatch {
+			t.Errorf("Expected ErrAlgorithmMismatch verifying with ES256 but got %v", err)
+		}
+	}
+
+	if _, err := ES256.Sign(payload, prepared); err != ErrInvalidKey {
+		t.Errorf("Expected ErrInvalidKey using an EdDSA prepared key with ES256 but got %v", err)
+	}
+
+	// zero values are rejected rather than panicking
+	if _, err := EdDSA.Sign(payload, Ed25519PrivateKey{}); err != ErrNotEdPrivateKey {
+		t.Errorf("Expected ErrNotEdPrivateKey but got %v", err)
+	}
+
+	if err := EdDSA.Verify(payload, segments[2], Ed25519PublicKey{}); err != ErrNotEdPublicKey {
+		t.Errorf("Expected ErrNotEdPublicKey but got %v", err)
+	}
+
+	if _, err := EdDSA.PrepareKey(Ed25519PrivateKey{}); err != ErrNotEdPrivateKey {
+		t.Errorf("Expected ErrNotEdPrivateKey but got %v", err)
+	}
+
+	if _, err := EdDSA.PrepareKey(Ed25519PublicKey{}); err != ErrNotEdPublicKey {
+		t.Errorf("Expected ErrNotEdPublicKey but got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+)
+
+// ErrUnsupportedAlgorithm is returned when asked to generate a key for an
+// algorithm that GenerateKey doesn't know about
+var ErrUnsupportedAlgorithm = errors.New("The algorithm is not supported")
+
+// GeneratedKey is a new key for an algorithm in each of the forms needed to
+// sign with it and to publish it. KeyID is the JWK thumbprint of the key and
+// is also set as the "kid" of both JWKs.
+type GeneratedKey struct {
+	KeyID     string
+	Algorithm SigningAlgorithm
+
+	// PrivateKey is a byte array for the HMAC algorithms. PublicKey,
+	// PublicJWK and the PEM encodings are nil for them since the secret
+	// mustn't be published.
+	PrivateKey crypto.PrivateKey
+	PublicKey  crypto.PublicKey
+
+	PrivateJWK *JWK
+	PublicJWK  *JWK
+
+	// PrivatePEM is PKCS8 and PublicPEM is PKIX
+	PrivatePEM []byte
+	PublicPEM  []byte
+}
+
+// GenerateKey generates a new key for alg, which may be one of the HMAC, RSA,
+// ECDSA or EdDSA algorithms. HMAC secrets are as long as the output of the
+// algorithm's hash function, RSA keys are MinRSAKeySize bits but at least
+// 2048 bits, and EC keys are on the curve required by the algorithm.
+func GenerateKey(alg SigningAlgorithm) (*GeneratedKey, error) {
+	var (
+		private crypto.PrivateKey
+		err     error
+	)
+
+	switch a := alg.(type) {
+	case *SigningAlgorithmHMAC:
+		secret := make([]byte, a.hash.Size())
+		_, err = rand.Read(secret)
+		private = secret
+	case *SigningAlgorithmRSA:
+		bits := MinRSAKeySize
+		if bits < 2048 {
+			bits = 2048
+		}
+
+		private, err = rsa.GenerateKey(rand.Reader, bits)
+	case *SigningAlgorithmECDSA:
+		private, err = ecdsa.GenerateKey(a.curve, rand.Reader)
+	case *SigningAlgorithmEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	key := &GeneratedKey{
+		Algorithm:  alg,
+		PrivateKey: private,
+	}
+
+	if key.PrivateJWK, err = NewJWK(private); err != nil {
+		return nil, err
+	}
+
+	if key.KeyID, err = key.PrivateJWK.Thumbprint(); err != nil {
+		return nil, err
+	}
+
+	key.PrivateJWK.Use = "sig"
+	key.PrivateJWK.Algorithm = alg.Name()
+	key.PrivateJWK.KeyID = key.KeyID
+
+	signer, ok := private.(crypto.Signer)
+
+	if !ok {
+		// a symmetric key
+		return key, nil
+	}
+
+	key.PublicKey = signer.Public()
+	key.PublicJWK = key.PrivateJWK.Public()
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key.PrivatePEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	if der, err = x509.MarshalPKIXPublicKey(key.PublicKey); err != nil {
+		return nil, err
+	}
+
+	key.PublicPEM = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	return key, nil
+}
@@ -0,0 +1,68 @@
+package jwt
+
+import (
+	"testing"
+)
+
+func TestGenerateKey(t *testing.T) {
+	for _, alg := range []SigningAlgorithm{HS256, HS512, RS256, ES256, ES384, ES512, EdDSA} {
+		key, err := GenerateKey(alg)
+
+		if err != nil {
+			t.Errorf("[%v] Unexpected error: %v", alg.Name(), err)
+			continue
+		}
+
+		if key.KeyID == "" || key.PrivateJWK.KeyID != key.KeyID || key.PrivateJWK.Algorithm != alg.Name() {
+			t.Errorf("[%v] Expected the JWK to have the kid and alg set", alg.Name())
+		}
+
+		tok := NewToken(alg).(HeaderToken)
+		tok.SetHeader("kid", key.KeyID)
+
+		encoded, err := tok.Encode(key.PrivateKey)
+
+		if err != nil {
+			t.Errorf("[%v] Error while signing: %v", alg.Name(), err)
+			continue
+		}
+
+		if _, ok := alg.(*SigningAlgorithmHMAC); ok {
+			if key.PublicJWK != nil || key.PublicPEM != nil || key.PrivatePEM != nil {
+				t.Errorf("[%v] Expected no public key for a secret", alg.Name())
+			}
+
+			if _, err := ParseToken(encoded, alg, key.PrivateKey); err != nil {
+				t.Errorf("[%v] Error while verifying: %v", alg.Name(), err)
+			}
+
+			continue
+		}
+
+		if key.PublicJWK.IsPrivate() || key.PublicJWK.KeyID != key.KeyID {
+			t.Errorf("[%v] Expected a public JWK with the kid set", alg.Name())
+		}
+
+		fromJWK, err := key.PublicJWK.Key()
+
+		if err != nil {
+			t.Errorf("[%v] Unexpected error: %v", alg.Name(), err)
+			continue
+		}
+
+		for _, verifyKey := range []interface{}{key.PublicKey, fromJWK, key.PublicPEM} {
+			if _, err := ParseToken(encoded, alg, verifyKey); err != nil {
+				t.Errorf("[%v] Error while verifying with %T: %v", alg.Name(), verifyKey, err)
+			}
+		}
+
+		// the PEM encoded private key signs too
+		if _, err := tok.Encode(key.PrivatePEM); err != nil {
+			t.Errorf("[%v] Error while signing with the PEM: %v", alg.Name(), err)
+		}
+	}
+
+	if _, err := GenerateKey(None); err != ErrUnsupportedAlgorithm {
+		t.Errorf("Expected ErrUnsupportedAlgorithm but got %v", err)
+	}
+}
@@ -3,6 +3,8 @@ package jwt
 import (
 	"crypto"
 	"crypto/hmac"
+	"hash"
+	"sync"
 )
 
 // SigningAlgorithmHMAC represents an HMAC signing algorithm
@@ -24,18 +26,108 @@ func (alg *SigningAlgorithmHMAC) Name() string {
 	return alg.name
 }
 
-func (alg *SigningAlgorithmHMAC) sign(payload string, key interface{}) ([]byte, error) {
-	var byteArray []byte
+// hmacKey is the key held by a PreparedKey for an HMAC algorithm. hmac.New
+// computes the keyed inner and outer state, so hashers are pooled and reset
+// rather than being recreated for every signature.
+type hmacKey struct {
+	hashers sync.Pool
+}
+
+// sum appends the HMAC of payload to dst
+func (k *hmacKey) sum(dst []byte, payload []byte) []byte {
+	hasher := k.hashers.Get().(hash.Hash)
+	hasher.Reset()
+	hasher.Write(payload)
+	dst = hasher.Sum(dst)
+	k.hashers.Put(hasher)
+
+	return dst
+}
+
+// secret returns the secret held by a byte array, string or HMACKey key
+func (alg *SigningAlgorithmHMAC) secret(key interface{}) ([]byte, error) {
+	var secret []byte
 
 	switch k := key.(type) {
 	case []byte:
-		byteArray = k
+		secret = k
 	case string:
-		byteArray = []byte(k)
+		secret = []byte(k)
+	case HMACKey:
+		if !allowsAlgorithm(k.Algorithms, alg) {
+			return nil, ErrAlgorithmMismatch
+		}
+
+		secret = k.Secret
 	default:
+		return nil, invalidKey(key)
+	}
+
+	// refuse to use an encoded asymmetric key as a secret, an HMACKey is
+	// trusted to hold a secret
+	if _, ok := key.(HMACKey); !ok && looksLikeAsymmetricKey(secret) {
+		return nil, ErrAlgorithmMismatch
+	}
+
+	if len(secret) < alg.minKeySize() {
+		return nil, ErrWeakKey
+	}
+
+	return secret, nil
+}
+
+// minKeySize returns the minimum length of a secret in bytes
+func (alg *SigningAlgorithmHMAC) minKeySize() int {
+	if MinHMACKeySize == 0 {
+		return alg.hash.Size()
+	}
+
+	return MinHMACKeySize
+}
+
+// PrepareKey takes either a byte array key, a string key or an HMACKey and
+// returns a PreparedKey that can be reused across calls to Sign and Verify,
+// including concurrently from multiple goroutines.
+func (alg *SigningAlgorithmHMAC) PrepareKey(key interface{}) (*PreparedKey, error) {
+	secret, err := alg.secret(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// take a copy so later changes to the key don't affect the prepared key
+	secret = append([]byte(nil), secret...)
+
+	hashFunc, err := newHashFunc(alg.hash)
+
+	if err != nil {
+		return nil, err
+	}
+
+	k := &hmacKey{}
+	k.hashers.New = func() interface{} {
+		return hmac.New(hashFunc, secret)
+	}
+
+	return &PreparedKey{hash: alg.hash, hashFunc: hashFunc, key: k}, nil
+}
+
+// sign appends the signature to dst
+func (alg *SigningAlgorithmHMAC) sign(dst []byte, payload []byte, key interface{}) ([]byte, error) {
+	if pk, ok := key.(*PreparedKey); ok {
+		if hk, ok := pk.key.(*hmacKey); ok && pk.hash == alg.hash {
+			return hk.sum(dst, payload), nil
+		}
+
 		return nil, ErrInvalidKey
 	}
 
+	byteArray, err := alg.secret(key)
+
+	if err != nil {
+		return nil, err
+	}
+
 	// byteArray now holds the key
 
 	// hmac.New expects a function to return the hash.Hash so we need to get a
@@ -47,44 +139,62 @@ func (alg *SigningAlgorithmHMAC) sign(payload string, key interface{}) ([]byte,
 	}
 
 	hasher := hmac.New(hashFunc, byteArray)
-	hasher.Write([]byte(payload))
+	hasher.Write(payload)
 
-	return hasher.Sum(nil), nil
+	return hasher.Sum(dst), nil
 }
 
-// Sign takes a string payload and either a byte array key or a string key and
-// returns the signature as a string or an error
+// Sign takes a string payload and either a byte array key, a string key, an
+// HMACKey or a PreparedKey and returns the signature as a string or an error
 func (alg *SigningAlgorithmHMAC) Sign(payload string, key interface{}) (string, error) {
 	var (
 		sigBytes []byte
 		err      error
 	)
 
-	if sigBytes, err = alg.sign(payload, key); err == nil {
+	if sigBytes, err = alg.sign(nil, []byte(payload), key); err == nil {
 		return encode(sigBytes), nil
 	}
 
 	return "", err
 }
 
+// SignBytes is like Sign but works on byte slices and returns the raw
+// signature
+func (alg *SigningAlgorithmHMAC) SignBytes(payload []byte, key interface{}) ([]byte, error) {
+	return alg.sign(nil, payload, key)
+}
+
 // Verify calculates the signature and checks that it matches.
 func (alg *SigningAlgorithmHMAC) Verify(payload string, signature string, key interface{}) error {
 	var (
-		checkSig []byte
-		origSig  []byte
-		err      error
+		origSig []byte
+		err     error
 	)
 
-	if checkSig, err = alg.sign(payload, key); err != nil {
+	if origSig, err = decode(signature); err != nil {
 		return err
 	}
 
-	if origSig, err = decode(signature); err != nil {
+	return alg.VerifyBytes([]byte(payload), origSig, key)
+}
+
+// VerifyBytes is like Verify but works on byte slices and takes the raw
+// signature
+func (alg *SigningAlgorithmHMAC) VerifyBytes(payload []byte, signature []byte, key interface{}) error {
+	var (
+		// large enough for SHA-512 so that checkSig doesn't escape
+		buf      [64]byte
+		checkSig []byte
+		err      error
+	)
+
+	if checkSig, err = alg.sign(buf[:0], payload, key); err != nil {
 		return err
 	}
 
 	// we have generated the signature, lets compare them
-	if hmac.Equal(checkSig, origSig) {
+	if hmac.Equal(checkSig, signature) {
 		return nil
 	}
 
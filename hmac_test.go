@@ -2,6 +2,7 @@ package jwt
 
 import (
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -110,6 +111,14 @@ func TestInvalidSignature(t *testing.T) {
 func TestStringKey(t *testing.T) {
 	segments := strings.Split(hmacStringKeyTest, ".")
 
+	// "secret" is too short to be accepted by default
+	if _, err := HS256.Sign(strings.Join(segments[0:2], "."), "secret"); err != ErrWeakKey {
+		t.Errorf("Expected ErrWeakKey but got %v", err)
+	}
+
+	defer func(size int) { MinHMACKeySize = size }(MinHMACKeySize)
+	MinHMACKeySize = 6
+
 	sig, err := HS256.Sign(
 		strings.Join(segments[0:2], "."),
 		"secret",
@@ -123,3 +132,142 @@ func TestStringKey(t *testing.T) {
 		t.Errorf("[%v] Incorrect signature.\nwas:\n%v\nexpecting:\n%v", "HS256", sig, segments[2])
 	}
 }
+
+func TestHMACPreparedKey(t *testing.T) {
+	for _, test := range []struct {
+		token string
+		alg   *SigningAlgorithmHMAC
+	}{
+		{hs256Test, HS256},
+		{hs384Test, HS384},
+		{hs512Test, HS512},
+	} {
+		segments := strings.Split(test.token, ".")
+		payload := strings.Join(segments[0:2], ".")
+
+		key, err := test.alg.PrepareKey(hmacTestKey)
+
+		if err != nil {
+			t.Fatalf("[%v] Error while preparing key: %v", test.alg.Name(), err)
+		}
+
+		sig, err := test.alg.Sign(payload, key)
+
+		if err != nil {
+			t.Errorf("[%v] Error while signing token: %v", test.alg.Name(), err)
+		}
+
+		if sig != segments[2] {
+			t.Errorf("[%v] Incorrect signature.\nwas:\n%v\nexpecting:\n%v", test.alg.Name(), sig, segments[2])
+		}
+
+		if err := test.alg.Verify(payload, segments[2], key); err != nil {
+			t.Errorf("[%v] Error while verifying signature: %v", test.alg.Name(), err)
+		}
+	}
+
+	key, _ := HS256.PrepareKey(hmacTestKey)
+	segments := strings.Split(hs512Test, ".")
+
+	if err := HS512.Verify(strings.Join(segments[0:2], "."), segments[2], key); err != ErrInvalidKey {
+		t.Errorf("Expected ErrInvalidKey using key prepared for another algorithm but got %v", err)
+	}
+}
+
+func TestHMACPreparedKeyConcurrent(t *testing.T) {
+	key, err := HS256.PrepareKey(hmacTestKey)
+
+	if err != nil {
+		t.Fatalf("Error while preparing key: %v", err)
+	}
+
+	valid := strings.Split(hs256Test, ".")
+	invalid := strings.Split(hmacInvalidTest, ".")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if err := HS256.Verify(strings.Join(valid[0:2], "."), valid[2], key); err != nil {
+					t.Errorf("Error while verifying signature: %v", err)
+				}
+
+				if err := HS256.Verify(strings.Join(invalid[0:2], "."), invalid[2], key); err == nil {
+					t.Errorf("Invalid signature passed verification")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func benchmarkHMACVerify(b *testing.B, key interface{}) {
+	segments := strings.Split(hs256Test, ".")
+	payload := []byte(strings.Join(segments[0:2], "."))
+	sig, _ := decode(segments[2])
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := HS256.VerifyBytes(payload, sig, key); err != nil {
+				// Fatal mustn't be called from the RunParallel goroutines
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkHS256Verify(b *testing.B) {
+	benchmarkHMACVerify(b, hmacTestKey)
+}
+
+func BenchmarkHS256VerifyPrepared(b *testing.B) {
+	key, err := HS256.PrepareKey(hmacTestKey)
+
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarkHMACVerify(b, key)
+}
+
+func TestHMACMinKeySize(t *testing.T) {
+	segments := strings.Split(hs512Test, ".")
+	payload := strings.Join(segments[0:2], ".")
+
+	// secrets must be at least as long as the hash output by default
+	for _, test := range []struct {
+		alg  *SigningAlgorithmHMAC
+		size int
+	}{
+		{HS256, 32},
+		{HS384, 48},
+		{HS512, 64},
+	} {
+		if _, err := test.alg.Sign(payload, hmacTestKey[:test.size]); err != nil {
+			t.Errorf("[%v] Error while signing with a %v byte key: %v", test.alg.Name(), test.size, err)
+		}
+
+		if _, err := test.alg.Sign(payload, hmacTestKey[:test.size-1]); err != ErrWeakKey {
+			t.Errorf("[%v] Expected ErrWeakKey signing with a %v byte key but got %v", test.alg.Name(), test.size-1, err)
+		}
+
+		if err := test.alg.Verify(payload, segments[2], hmacTestKey[:test.size-1]); err != ErrWeakKey {
+			t.Errorf("[%v] Expected ErrWeakKey verifying with a %v byte key but got %v", test.alg.Name(), test.size-1, err)
+		}
+
+		if _, err := test.alg.PrepareKey(HMACKey{Secret: hmacTestKey[:test.size-1]}); err != ErrWeakKey {
+			t.Errorf("[%v] Expected ErrWeakKey preparing a %v byte key but got %v", test.alg.Name(), test.size-1, err)
+		}
+	}
+
+	defer func(size int) { MinHMACKeySize = size }(MinHMACKeySize)
+	MinHMACKeySize = 128
+
+	if _, err := HS512.Sign(payload, hmacTestKey); err != ErrWeakKey {
+		t.Errorf("Expected ErrWeakKey with a raised minimum but got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+// Errors returned by checkStrictJSON
+var (
+	errInvalidUTF8  = errors.New("JSON contains invalid UTF-8")
+	errNotObject    = errors.New("JSON is not an object")
+	errDuplicateKey = errors.New("JSON object contains a duplicate key")
+	errTrailingData = errors.New("JSON is followed by trailing data")
+)
+
+// checkStrictJSON checks that data is a single JSON object encoded as valid
+// UTF-8 in which no object, at any depth, contains the same member name twice.
+// encoding/json accepts all of these, silently keeping the last value for
+// duplicate names, so parsers can disagree on what a token contains.
+func checkStrictJSON(data []byte) error {
+	if !utf8.Valid(data) {
+		return errInvalidUTF8
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	tok, err := dec.Token()
+
+	if err != nil {
+		return err
+	}
+
+	if tok != json.Delim('{') {
+		return errNotObject
+	}
+
+	if err = checkStrictObject(dec); err != nil {
+		return err
+	}
+
+	if _, err = dec.Token(); err != io.EOF {
+		return errTrailingData
+	}
+
+	return nil
+}
+
+// checkStrictObject checks the members of an object whose opening brace has
+// already been read
+func checkStrictObject(dec *json.Decoder) error {
+	keys := make(map[string]struct{})
+
+	for dec.More() {
+		tok, err := dec.Token()
+
+		if err != nil {
+			return err
+		}
+
+		key, _ := tok.(string)
+
+		if _, ok := keys[key]; ok {
+			return errDuplicateKey
+		}
+
+		keys[key] = struct{}{}
+
+		if err = checkStrictValue(dec); err != nil {
+			return err
+		}
+	}
+
+	// closing brace
+	_, err := dec.Token()
+
+	return err
+}
+
+func checkStrictValue(dec *json.Decoder) error {
+	tok, err := dec.Token()
+
+	if err != nil {
+		return err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		return checkStrictObject(dec)
+	case json.Delim('['):
+		for dec.More() {
+			if err = checkStrictValue(dec); err != nil {
+				return err
+			}
+		}
+
+		// closing bracket
+		_, err = dec.Token()
+	}
+
+	return err
+}
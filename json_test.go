@@ -0,0 +1,38 @@
+package jwt
+
+import (
+	"testing"
+)
+
+func TestCheckStrictJSON(t *testing.T) {
+	for _, test := range []struct {
+		json string
+		err  error
+	}{
+		{`{}`, nil},
+		{` {"a":1,"b":[{"a":1},{"a":2}],"c":{"a":{"b":null}}} `, nil},
+		{`{"a":1,"A":2}`, nil},
+
+		{`{"a":1,"a":2}`, errDuplicateKey},
+		{`{"a":1,"\u0061":2}`, errDuplicateKey},
+		{`{"a":{"b":1,"b":1}}`, errDuplicateKey},
+		{`{"a":[{"b":1,"b":1}]}`, errDuplicateKey},
+
+		{"{\"a\":\"\xff\"}", errInvalidUTF8},
+		{`[]`, errNotObject},
+		{`null`, errNotObject},
+		{`"a"`, errNotObject},
+		{`{}{}`, errTrailingData},
+		{`{} 1`, errTrailingData},
+	} {
+		if err := checkStrictJSON([]byte(test.json)); err != test.err {
+			t.Errorf("Checking %s, expected %v but got %v", test.json, test.err, err)
+		}
+	}
+
+	for _, invalid := range []string{``, `{`, `{"a"}`, `{"a":}`, `{"a":1,}`} {
+		if err := checkStrictJSON([]byte(invalid)); err == nil {
+			t.Errorf("Checking %s, expected an error", invalid)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"math/big"
+)
+
+// ErrInvalidJWK is returned when a JWK is missing a required member or holds
+// an invalid key
+var ErrInvalidJWK = errors.New("The JWK is invalid")
+
+// JWK is a JSON Web Key as described in RFC 7517. Key values are base64url
+// encoded as described in RFC 7518 and RFC 8037.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+
+	// RSA keys
+	N  string `json:"n,omitempty"`
+	E  string `json:"e,omitempty"`
+	P  string `json:"p,omitempty"`
+	Q  string `json:"q,omitempty"`
+	DP string `json:"dp,omitempty"`
+	DQ string `json:"dq,omitempty"`
+	QI string `json:"qi,omitempty"`
+
+	// EC and OKP keys
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+
+	// the private exponent of an RSA key or the private key of an EC or OKP
+	// key
+	D string `json:"d,omitempty"`
+
+	// symmetric keys
+	K string `json:"k,omitempty"`
+}
+
+// JWKSet is a JWK Set as described in RFC 7517 section 5
+type JWKSet struct {
+	Keys []*JWK `json:"keys"`
+}
+
+// Key returns the key with the given "kid", or nil if there isn't one
+func (s *JWKSet) Key(kid string) *JWK {
+	for _, key := range s.Keys {
+		if key.KeyID == kid {
+			return key
+		}
+	}
+
+	return nil
+}
+
+// jwkCurves maps the JWK names of the supported curves to their OpenSSH
+// names, which are used to look up the curves
+var jwkCurves = map[string]string{
+	"P-256": "nistp256",
+	"P-384": "nistp384",
+	"P-521": "nistp521",
+}
+
+// NewJWK creates a JWK from an *rsa.PrivateKey, *rsa.PublicKey,
+// *ecdsa.PrivateKey, *ecdsa.PublicKey, ed25519.PrivateKey,
+// ed25519.PublicKey or a byte array holding a symmetric key. The "use",
+// "alg" and "kid" members are left for the caller to fill in.
+func NewJWK(key interface{}) (*JWK, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if len(k.Primes) != 2 {
+			return nil, ErrInvalidKey
+		}
+
+		// the CRT values are computed here rather than by Precompute, which
+		// would modify the caller's key
+		p, q := k.Primes[0], k.Primes[1]
+		one := big.NewInt(1)
+
+		dp := new(big.Int).Mod(k.D, new(big.Int).Sub(p, one))
+		dq := new(big.Int).Mod(k.D, new(big.Int).Sub(q, one))
+		qi := new(big.Int).ModInverse(q, p)
+
+		jwk, _ := NewJWK(&k.PublicKey)
+		jwk.D = encode(k.D.Bytes())
+		jwk.P = encode(p.Bytes())
+		jwk.Q = encode(q.Bytes())
+		jwk.DP = encode(dp.Bytes())
+		jwk.DQ = encode(dq.Bytes())
+		jwk.QI = encode(qi.Bytes())
+
+		return jwk, nil
+	case *rsa.PublicKey:
+		return &JWK{
+			KeyType: "RSA",
+			N:       encode(k.N.Bytes()),
+			E:       encode(big.NewInt(int64(k.E)).Bytes()),
+		}, nil
+	case *ecdsa.PrivateKey:
+		jwk, err := NewJWK(&k.PublicKey)
+
+		if err != nil {
+			return nil, err
+		}
+
+		size := (k.Curve.Params().BitSize + 7) / 8
+		jwk.D = encode(k.D.FillBytes(make([]byte, size)))
+
+		return jwk, nil
+	case *ecdsa.PublicKey:
+		name := k.Curve.Params().Name
+
+		if _, ok := jwkCurves[name]; !ok {
+			return nil, ErrInvalidCurve
+		}
+
+		// coordinates are padded to the size of the curve
+		size := (k.Curve.Params().BitSize + 7) / 8
+
+		return &JWK{
+			KeyType: "EC",
+			Curve:   name,
+			X:       encode(k.X.FillBytes(make([]byte, size))),
+			Y:       encode(k.Y.FillBytes(make([]byte, size))),
+		}, nil
+	case ed25519.PrivateKey:
+		if len(k) != ed25519.PrivateKeySize {
+			return nil, ErrNotEdPrivateKey
+		}
+
+		jwk, _ := NewJWK(k.Public())
+		jwk.D = encode(k.Seed())
+
+		return jwk, nil
+	case ed25519.PublicKey:
+		if len(k) != ed25519.PublicKeySize {
+			return nil, ErrNotEdPublicKey
+		}
+
+		return &JWK{KeyType: "OKP", Curve: "Ed25519", X: encode(k)}, nil
+	case []byte:
+		return &JWK{KeyType: "oct", K: encode(k)}, nil
+	}
+
+	return nil, ErrInvalidKey
+}
+
+// IsPrivate reports whether the JWK holds a private or symmetric key
+func (k *JWK) IsPrivate() bool {
+	return k.D != "" || k.KeyType == "oct"
+}
+
+// Public returns a copy of the JWK holding only its public key, or nil if it
+// holds a symmetric key
+func (k *JWK) Public() *JWK {
+	if k.KeyType == "oct" {
+		return nil
+	}
+
+	public := *k
+	public.D, public.P, public.Q, public.DP, public.DQ, public.QI = "", "", "", "", "", ""
+
+	return &public
+}
+
+// Key returns the key held by the JWK as an *rsa.PrivateKey,
+// *rsa.PublicKey, *ecdsa.PrivateKey, *ecdsa.PublicKey, ed25519.PrivateKey,
+// ed25519.PublicKey or, for symmetric keys, a byte array
+func (k *JWK) Key() (interface{}, error) {
+	switch k.KeyType {
+	case "RSA":
+		return k.rsaKey()
+	case "EC":
+		return k.ecKey()
+	case "OKP":
+		return k.edKey()
+	case "oct":
+		secret, err := decode(k.K)
+
+		if err != nil || len(secret) == 0 {
+			return nil, ErrInvalidJWK
+		}
+
+		return secret, nil
+	}
+
+	return nil, ErrInvalidJWK
+}
+
+// decodeInt decodes a base64url encoded unsigned big-endian integer
+func decodeInt(s string) (*big.Int, error) {
+	b, err := decode(s)
+
+	if err != nil || len(b) == 0 {
+		return nil, ErrInvalidJWK
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
+
+func (k *JWK) rsaKey() (interface{}, error) {
+	n, err := decodeInt(k.N)
+
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := decodeInt(k.E)
+
+	if err != nil || e.BitLen() > 31 {
+		return nil, ErrInvalidJWK
+	}
+
+	public := rsa.PublicKey{N: n, E: int(e.Int64())}
+
+	if k.D == "" {
+		return &public, nil
+	}
+
+	var ints [3]*big.Int
+	for i, s := range []string{k.D, k.P, k.Q} {
+		if ints[i], err = decodeInt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	private := &rsa.PrivateKey{
+		PublicKey: public,
+		D:         ints[0],
+		Primes:    []*big.Int{ints[1], ints[2]},
+	}
+
+	if err := private.Validate(); err != nil {
+		return nil, ErrInvalidJWK
+	}
+
+	// dp, dq and qi are recomputed rather than trusted
+	private.Precompute()
+
+	return private, nil
+}
+
+func (k *JWK) ecKey() (interface{}, error) {
+	curveName, ok := jwkCurves[k.Curve]
+
+	if !ok {
+		return nil, ErrInvalidJWK
+	}
+
+	size := (sshCurves[curveName].curve.Params().BitSize + 7) / 8
+
+	x, errX := decode(k.X)
+	y, errY := decode(k.Y)
+
+	if errX != nil || errY != nil || len(x) != size || len(y) != size {
+		return nil, ErrInvalidJWK
+	}
+
+	// the uncompressed form of the point
+	point := append(append([]byte{4}, x...), y...)
+
+	if k.D == "" {
+		public, err := parseSSHECPoint(curveName, point)
+
+		if err != nil {
+			return nil, ErrInvalidJWK
+		}
+
+		return public, nil
+	}
+
+	d, err := decode(k.D)
+
+	if err != nil || len(d) != size {
+		return nil, ErrInvalidJWK
+	}
+
+	private, err := parseSSHECPrivateKey(curveName, point, new(big.Int).SetBytes(d))
+
+	if err != nil {
+		return nil, ErrInvalidJWK
+	}
+
+	return private, nil
+}
+
+func (k *JWK) edKey() (interface{}, error) {
+	if k.Curve != "Ed25519" {
+		return nil, ErrInvalidJWK
+	}
+
+	x, err := decode(k.X)
+
+	if err != nil || len(x) != ed25519.PublicKeySize {
+		return nil, ErrInvalidJWK
+	}
+
+	if k.D == "" {
+		return ed25519.PublicKey(x), nil
+	}
+
+	seed, err := decode(k.D)
+
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, ErrInvalidJWK
+	}
+
+	private := ed25519.NewKeyFromSeed(seed)
+
+	if !private.Public().(ed25519.PublicKey).Equal(ed25519.PublicKey(x)) {
+		return nil, ErrInvalidJWK
+	}
+
+	return private, nil
+}
+
+// Thumbprint computes the SHA-256 JWK Thumbprint described in RFC 7638,
+// which is suitable for use as a "kid"
+func (k *JWK) Thumbprint() (string, error) {
+	var members map[string]string
+
+	switch k.KeyType {
+	case "RSA":
+		members = map[string]string{"e": k.E, "kty": k.KeyType, "n": k.N}
+	case "EC":
+		members = map[string]string{"crv": k.Curve, "kty": k.KeyType, "x": k.X, "y": k.Y}
+	case "OKP":
+		members = map[string]string{"crv": k.Curve, "kty": k.KeyType, "x": k.X}
+	case "oct":
+		members = map[string]string{"k": k.K, "kty": k.KeyType}
+	default:
+		return "", ErrInvalidJWK
+	}
+
+	for _, v := range members {
+		if v == "" {
+			return "", ErrInvalidJWK
+		}
+	}
+
+	// maps are marshalled with their keys sorted and without whitespace, as
+	// the thumbprint requires
+	data, err := json.Marshal(members)
+
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return encode(sum[:]), nil
+}
@@ -0,0 +1,154 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"testing"
+)
+
+// from RFC 7638 section 3.1
+const (
+	thumbprintN = "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw"
+	thumbprint  = "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"
+)
+
+func TestJWKThumbprint(t *testing.T) {
+	jwk := &JWK{KeyType: "RSA", N: thumbprintN, E: "AQAB", Algorithm: "RS256", KeyID: "2011-04-29"}
+
+	if tp, err := jwk.Thumbprint(); err != nil || tp != thumbprint {
+		t.Errorf("Expected thumbprint %v but got %v (%v)", thumbprint, tp, err)
+	}
+
+	if _, err := (&JWK{KeyType: "RSA", N: thumbprintN}).Thumbprint(); err != ErrInvalidJWK {
+		t.Errorf("Expected ErrInvalidJWK for a JWK without e but got %v", err)
+	}
+}
+
+// privateKeyEqual and publicKeyEqual are implemented by the keys in the
+// standard library
+type (
+	privateKeyEqual interface {
+		Equal(x crypto.PrivateKey) bool
+	}
+
+	publicKeyEqual interface {
+		Equal(x crypto.PublicKey) bool
+	}
+)
+
+func TestJWKRoundTrip(t *testing.T) {
+	rsaKey, _ := ParseRSAPrivateKeyFromPEM([]byte(rsaPrivateKey))
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	for _, private := range []interface{}{rsaKey, ecKey, edKey} {
+		jwk, err := NewJWK(private)
+
+		if err != nil {
+			t.Errorf("[%T] Unexpected error: %v", private, err)
+			continue
+		}
+
+		// through JSON and back
+		data, _ := json.Marshal(jwk)
+
+		var parsed JWK
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			t.Fatal(err)
+		}
+
+		if !parsed.IsPrivate() {
+			t.Errorf("[%T] Expected a private JWK", private)
+		}
+
+		key, err := parsed.Key()
+
+		if err != nil || !key.(privateKeyEqual).Equal(private) {
+			t.Errorf("[%T] The key doesn't match (%v)", private, err)
+		}
+
+		public := parsed.Public()
+
+		if public.IsPrivate() || public.D != "" || public.P != "" || public.QI != "" {
+			t.Errorf("[%T] The public JWK holds private parameters", private)
+		}
+
+		publicKey, err := public.Key()
+
+		if err != nil || !publicKey.(publicKeyEqual).Equal(private.(crypto.Signer).Public()) {
+			t.Errorf("[%T] The public key doesn't match (%v)", private, err)
+		}
+
+		// the thumbprint only covers the public key
+		tp1, _ := parsed.Thumbprint()
+		tp2, _ := public.Thumbprint()
+
+		if tp1 == "" || tp1 != tp2 {
+			t.Errorf("[%T] Expected matching thumbprints, got %v and %v", private, tp1, tp2)
+		}
+	}
+
+	jwk, _ := NewJWK([]byte("secret"))
+
+	if jwk.KeyType != "oct" || jwk.Public() != nil || !jwk.IsPrivate() {
+		t.Errorf("Expected a private oct JWK without a public key")
+	}
+
+	if key, err := jwk.Key(); err != nil || string(key.([]byte)) != "secret" {
+		t.Errorf("Expected the secret but got %v (%v)", key, err)
+	}
+}
+
+func TestJWKInvalid(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	valid, _ := NewJWK(ecKey)
+	otherJWK, _ := NewJWK(other)
+
+	mismatched := *valid
+	mismatched.D = otherJWK.D
+
+	offCurve := *valid
+	offCurve.D = ""
+	offCurve.X = otherJWK.X
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+	badPrime, _ := NewJWK(rsaKey)
+	badPrime.P = badPrime.Q
+
+	tests := []*JWK{
+		{KeyType: "unknown"},
+		{KeyType: "EC", Curve: "P-224", X: valid.X, Y: valid.Y},
+		{KeyType: "EC", Curve: "P-256", X: valid.X},
+		{KeyType: "OKP", Curve: "X25519", X: ed25519X},
+		{KeyType: "RSA", N: thumbprintN},
+		{KeyType: "oct"},
+		&mismatched,
+		&offCurve,
+		badPrime,
+	}
+
+	for i, jwk := range tests {
+		if _, err := jwk.Key(); err != ErrInvalidJWK {
+			t.Errorf("[%v] Expected ErrInvalidJWK but got %v", i, err)
+		}
+	}
+}
+
+func TestJWKSetKey(t *testing.T) {
+	set := &JWKSet{Keys: []*JWK{{KeyType: "oct", KeyID: "a"}, {KeyType: "oct", KeyID: "b"}}}
+
+	if key := set.Key("b"); key != set.Keys[1] {
+		t.Errorf("Expected the second key but got %v", key)
+	}
+
+	if key := set.Key("c"); key != nil {
+		t.Errorf("Expected no key but got %v", key)
+	}
+}
@@ -0,0 +1,113 @@
+package jwt
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JWKSHandler is an http.Handler that serves the public keys of a JWK Set,
+// for example at "/.well-known/jwks.json". Private key parameters are removed
+// and symmetric keys are never served, whatever Source returns. Responses
+// carry an ETag so that clients can revalidate with If-None-Match.
+type JWKSHandler struct {
+	// Source returns the keys to serve and is called for each request, for
+	// example KeyManager.JWKSet
+	Source func() (*JWKSet, error)
+
+	// MaxAge is how long clients may cache the keys. If it's zero clients
+	// must revalidate before each use.
+	MaxAge time.Duration
+}
+
+// NewJWKSHandler creates a JWKSHandler serving the keys returned by source
+func NewJWKSHandler(source func() (*JWKSet, error), maxAge time.Duration) *JWKSHandler {
+	return &JWKSHandler{Source: source, MaxAge: maxAge}
+}
+
+// publicJWKSet returns a copy of the set with only the public keys. A nil set
+// is treated as an empty one.
+func publicJWKSet(set *JWKSet) *JWKSet {
+	public := &JWKSet{Keys: []*JWK{}}
+
+	if set == nil {
+		return public
+	}
+
+	for _, key := range set.Keys {
+		if key == nil {
+			continue
+		}
+
+		if key := key.Public(); key != nil {
+			public.Keys = append(public.Keys, key)
+		}
+	}
+
+	return public
+}
+
+// etagMatch reports whether an If-None-Match header matches etag, using the
+// weak comparison required by RFC 9110
+func etagMatch(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *JWKSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	set, err := h.Source()
+
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(publicJWKSet(set))
+
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + encode(sum[:16]) + `"`
+
+	header := w.Header()
+	header.Set("ETag", etag)
+
+	if h.MaxAge > 0 {
+		header.Set("Cache-Control", "public, max-age="+strconv.FormatInt(int64(h.MaxAge/time.Second), 10))
+	} else {
+		header.Set("Cache-Control", "no-cache")
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" && etagMatch(inm, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	header.Set("Content-Type", "application/jwk-set+json")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	w.Write(body)
+}
@@ -0,0 +1,149 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestJWKSHandler(t *testing.T) {
+	ecKey, _ := GenerateKey(ES256)
+	hmacKey, _ := GenerateKey(HS256)
+
+	// a source that carelessly includes private keys
+	handler := NewJWKSHandler(func() (*JWKSet, error) {
+		return &JWKSet{Keys: []*JWK{ecKey.PrivateJWK, hmacKey.PrivateJWK}}, nil
+	}, time.Hour)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 but got %v", rec.Code)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/jwk-set+json" {
+		t.Errorf("Expected the JWK Set content type but got %v", ct)
+	}
+
+	if cc := rec.Header().Get("Cache-Control"); cc != "public, max-age=3600" {
+		t.Errorf("Expected Cache-Control public, max-age=3600 but got %v", cc)
+	}
+
+	var set JWKSet
+	if err := json.Unmarshal(rec.Body.Bytes(), &set); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(set.Keys) != 1 || set.Keys[0].KeyID != ecKey.KeyID || set.Keys[0].IsPrivate() {
+		t.Errorf("Expected only the public EC key but got %s", rec.Body.Bytes())
+	}
+
+	etag := rec.Header().Get("ETag")
+
+	for _, inm := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("If-None-Match", inm)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("[%v] Expected status 304 without a body but got %v", inm, rec.Code)
+		}
+
+		if rec.Header().Get("ETag") != etag {
+			t.Errorf("[%v] Expected the ETag to be sent with 304", inm)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `"stale"`)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for a stale ETag but got %v", rec.Code)
+	}
+}
+
+func TestJWKSHandlerMethods(t *testing.T) {
+	handler := NewJWKSHandler(func() (*JWKSet, error) {
+		return &JWKSet{}, nil
+	}, 0)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/", nil))
+
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Errorf("Expected status 200 without a body for HEAD but got %v", rec.Code)
+	}
+
+	if cc := rec.Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("Expected Cache-Control no-cache but got %v", cc)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, HEAD" {
+		t.Errorf("Expected status 405 but got %v", rec.Code)
+	}
+
+	failing := NewJWKSHandler(func() (*JWKSet, error) {
+		return nil, errors.New("unavailable")
+	}, 0)
+
+	rec = httptest.NewRecorder()
+	failing.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500 but got %v", rec.Code)
+	}
+}
+
+func TestJWKSHandlerKeyManager(t *testing.T) {
+	m, err := NewKeyManager(nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Rotate(RS256, time.Now(), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	NewJWKSHandler(m.JWKSet, time.Minute).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var set JWKSet
+	if err := json.Unmarshal(rec.Body.Bytes(), &set); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(set.Keys) != 1 || set.Keys[0].Algorithm != "RS256" || set.Keys[0].Use != "sig" {
+		t.Errorf("Expected the RS256 key but got %s", rec.Body.Bytes())
+	}
+}
+
+func TestJWKSHandlerNilSet(t *testing.T) {
+	for _, source := range []func() (*JWKSet, error){
+		func() (*JWKSet, error) { return nil, nil },
+		func() (*JWKSet, error) { return &JWKSet{Keys: []*JWK{nil}}, nil },
+	} {
+		rec := httptest.NewRecorder()
+		NewJWKSHandler(source, 0).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 but got %v", rec.Code)
+		}
+
+		if body := rec.Body.String(); body != `{"keys":[]}` {
+			t.Errorf("Expected an empty JWK Set but got %s", body)
+		}
+	}
+}
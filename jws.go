@@ -0,0 +1,352 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// Errors relating to the JWS JSON serialization
+var (
+	ErrJWSMalformed    = errors.New("The JWS is malformed")
+	ErrDuplicateHeader = errors.New("The header parameter is in both the protected and unprotected headers")
+	ErrNoSignatures    = errors.New("The JWS has no signatures")
+	ErrNotFlattened    = errors.New("The flattened syntax requires exactly one signature")
+)
+
+// JWS is a JSON Web Signature using the JSON serialization described in RFC
+// 7515 section 7.2. Unlike a token, a JWS may have any payload and may carry
+// several signatures, each with its own protected and unprotected headers.
+// It's marshalled using the general syntax, or the flattened syntax with
+// MarshalFlattened.
+type JWS struct {
+	// payload is kept base64url encoded as it appeared, since it's part of
+	// the signing input
+	payload    string
+	Signatures []*Signature
+}
+
+// Signature is one of the signatures of a JWS
+type Signature struct {
+	// protected is the encoded protected header, which is part of the
+	// signing input
+	protected string
+	payload   string
+	signature []byte
+
+	// Protected holds the integrity protected header parameters and Header
+	// the unprotected ones. They must not share a parameter.
+	Protected map[string]interface{}
+	Header    map[string]interface{}
+}
+
+// jwsJSON is the JSON form of a JWS. The fields of the embedded signature
+// hold the flattened syntax.
+type jwsJSON struct {
+	Payload    *string          `json:"payload"`
+	Signatures []*signatureJSON `json:"signatures,omitempty"`
+	signatureJSON
+}
+
+type signatureJSON struct {
+	Protected *string                `json:"protected,omitempty"`
+	Header    map[string]interface{} `json:"header,omitempty"`
+	Signature *string                `json:"signature,omitempty"`
+}
+
+// NewJWS creates a JWS with the payload and no signatures
+func NewJWS(payload []byte) *JWS {
+	return &JWS{payload: encode(payload)}
+}
+
+// Payload returns the decoded payload. It should only be trusted once a
+// signature has been verified.
+func (j *JWS) Payload() ([]byte, error) {
+	return decode(j.payload)
+}
+
+// Sign adds a signature over the payload. The "alg" parameter is added to the
+// protected header, and protected and unprotected, which may be nil, mustn't
+// share a parameter.
+func (j *JWS) Sign(alg SigningAlgorithm, key interface{}, protected map[string]interface{}, unprotected map[string]interface{}) (*Signature, error) {
+	return j.SignContext(context.Background(), alg, key, protected, unprotected)
+}
+
+// SignContext is like Sign but passes ctx through to the signing algorithm
+func (j *JWS) SignContext(ctx context.Context, alg SigningAlgorithm, key interface{}, protected map[string]interface{}, unprotected map[string]interface{}) (*Signature, error) {
+	s := &Signature{
+		payload:   j.payload,
+		Protected: map[string]interface{}{"alg": alg.Name()},
+		Header:    unprotected,
+	}
+
+	for name, v := range protected {
+		if name != "alg" {
+			s.Protected[name] = v
+		}
+	}
+
+	for name := range s.Header {
+		if _, ok := s.Protected[name]; ok {
+			return nil, ErrDuplicateHeader
+		}
+	}
+
+	jsonValue, err := json.Marshal(s.Protected)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.protected = encode(jsonValue)
+
+	if s.signature, err = signContext(ctx, alg, s.signingInput(), key); err != nil {
+		return nil, err
+	}
+
+	j.Signatures = append(j.Signatures, s)
+
+	return s, nil
+}
+
+// signingInput returns the protected header and payload that are signed
+func (s *Signature) signingInput() []byte {
+	input := make([]byte, 0, len(s.protected)+1+len(s.payload))
+	input = append(input, s.protected...)
+	input = append(input, '.')
+
+	return append(input, s.payload...)
+}
+
+// Get returns the value of a header parameter from the protected or
+// unprotected header
+func (s *Signature) Get(name string) interface{} {
+	if v, ok := s.Protected[name]; ok {
+		return v
+	}
+
+	return s.Header[name]
+}
+
+// Algorithm returns the "alg" header parameter
+func (s *Signature) Algorithm() string {
+	alg, _ := s.Get("alg").(string)
+	return alg
+}
+
+// KeyID returns the "kid" header parameter
+func (s *Signature) KeyID() string {
+	kid, _ := s.Get("kid").(string)
+	return kid
+}
+
+// Verify checks the signature using alg and key, which may be a *KeySet. The
+// signature's "alg" header must name alg, and its "crit" header mustn't
+// list any parameters since none are supported.
+func (s *Signature) Verify(alg SigningAlgorithm, key interface{}) error {
+	_, err := s.verify(context.Background(), alg, key)
+	return err
+}
+
+// VerifyContext is like Verify but passes ctx through to the signing
+// algorithm
+func (s *Signature) VerifyContext(ctx context.Context, alg SigningAlgorithm, key interface{}) error {
+	_, err := s.verify(ctx, alg, key)
+	return err
+}
+
+// verify checks the signature and returns the ID of the key in a KeySet that
+// verified it. alg may be nil when key is a KeySet of bound keys.
+func (s *Signature) verify(ctx context.Context, alg SigningAlgorithm, key interface{}) (string, error) {
+	if alg != nil && s.Algorithm() != alg.Name() {
+		return "", ErrAlgorithmMismatch
+	}
+
+	if _, ok := s.Header["crit"]; ok {
+		return "", ErrInvalidCritical
+	}
+
+	header := make(map[string]interface{}, len(s.Protected)+len(s.Header))
+	for name, v := range s.Header {
+		header[name] = v
+	}
+
+	for name, v := range s.Protected {
+		header[name] = v
+	}
+
+	// there are no handlers, so any critical parameter is unsupported
+	if errs := (&Parser{}).checkCrit(&token{header: header}, false); len(errs) > 0 {
+		return "", errs[0]
+	}
+
+	return verifyKey(ctx, alg, header, s.signingInput(), s.signature, key)
+}
+
+// Verify checks that at least one signature verifies with alg and key, and
+// returns the first that does. Signatures for other algorithms are skipped.
+func (j *JWS) Verify(alg SigningAlgorithm, key interface{}) (*Signature, error) {
+	return j.VerifyContext(context.Background(), alg, key)
+}
+
+// VerifyContext is like Verify but passes ctx through to the signing
+// algorithm
+func (j *JWS) VerifyContext(ctx context.Context, alg SigningAlgorithm, key interface{}) (*Signature, error) {
+	if len(j.Signatures) == 0 {
+		return nil, ErrNoSignatures
+	}
+
+	for _, s := range j.Signatures {
+		if _, err := s.verify(ctx, alg, key); err == nil {
+			return s, nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, ErrBadSignature
+}
+
+// toJSON returns the JSON form of the signature
+func (s *Signature) toJSON() *signatureJSON {
+	signature := encode(s.signature)
+	v := &signatureJSON{Header: s.Header, Signature: &signature}
+
+	if s.protected != "" {
+		v.Protected = &s.protected
+	}
+
+	return v
+}
+
+// MarshalJSON encodes the JWS using the general syntax
+func (j *JWS) MarshalJSON() ([]byte, error) {
+	if len(j.Signatures) == 0 {
+		return nil, ErrNoSignatures
+	}
+
+	v := &jwsJSON{Payload: &j.payload}
+
+	for _, s := range j.Signatures {
+		v.Signatures = append(v.Signatures, s.toJSON())
+	}
+
+	return json.Marshal(v)
+}
+
+// MarshalFlattened encodes the JWS using the flattened syntax, which is only
+// possible if it has exactly one signature
+func (j *JWS) MarshalFlattened() ([]byte, error) {
+	if len(j.Signatures) != 1 {
+		return nil, ErrNotFlattened
+	}
+
+	return json.Marshal(&jwsJSON{Payload: &j.payload, signatureJSON: *j.Signatures[0].toJSON()})
+}
+
+// UnmarshalJSON decodes a JWS in either the general or flattened syntax
+func (j *JWS) UnmarshalJSON(data []byte) error {
+	parsed, err := ParseJWS(data)
+
+	if err != nil {
+		return err
+	}
+
+	*j = *parsed
+
+	return nil
+}
+
+// ParseJWS parses a JWS in either the general or flattened JSON syntax. The
+// signatures aren't verified. Duplicate member names are rejected, as are
+// signatures whose protected and unprotected headers share a parameter or
+// that have no "alg" parameter.
+func ParseJWS(data []byte) (*JWS, error) {
+	if err := checkStrictJSON(data); err != nil {
+		return nil, ErrJWSMalformed
+	}
+
+	var v jwsJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, ErrJWSMalformed
+	}
+
+	flattened := v.Protected != nil || v.Header != nil || v.Signature != nil
+
+	if v.Payload == nil || flattened == (v.Signatures != nil) {
+		return nil, ErrJWSMalformed
+	}
+
+	if _, err := decode(*v.Payload); err != nil {
+		return nil, ErrJWSMalformed
+	}
+
+	signatures := v.Signatures
+	if flattened {
+		signatures = []*signatureJSON{&v.signatureJSON}
+	}
+
+	if len(signatures) == 0 {
+		return nil, ErrNoSignatures
+	}
+
+	j := &JWS{payload: *v.Payload}
+
+	for _, sj := range signatures {
+		s, err := sj.parse(j.payload)
+
+		if err != nil {
+			return nil, err
+		}
+
+		j.Signatures = append(j.Signatures, s)
+	}
+
+	return j, nil
+}
+
+// parse decodes and checks a signature
+func (sj *signatureJSON) parse(payload string) (*Signature, error) {
+	if sj == nil || sj.Signature == nil || (sj.Protected == nil && sj.Header == nil) {
+		return nil, ErrJWSMalformed
+	}
+
+	s := &Signature{payload: payload, Header: sj.Header}
+
+	var err error
+	if s.signature, err = decode(*sj.Signature); err != nil {
+		return nil, ErrJWSMalformed
+	}
+
+	if sj.Protected != nil {
+		s.protected = *sj.Protected
+
+		protected, err := decode(s.protected)
+
+		if err != nil {
+			return nil, ErrJWSMalformed
+		}
+
+		if err := checkStrictJSON(protected); err != nil {
+			return nil, ErrJWSMalformed
+		}
+
+		if err := json.Unmarshal(protected, &s.Protected); err != nil {
+			return nil, ErrJWSMalformed
+		}
+	}
+
+	for name := range s.Header {
+		if _, ok := s.Protected[name]; ok {
+			return nil, ErrDuplicateHeader
+		}
+	}
+
+	if s.Algorithm() == "" {
+		return nil, ErrJWSMalformed
+	}
+
+	return s, nil
+}
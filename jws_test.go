@@ -0,0 +1,181 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// from RFC 7515 appendix A.7, which reuses the signature from appendix A.3
+func es256FlattenedJWS() string {
+	segments := strings.Split(es256Test, ".")
+
+	return `{"payload":"` + segments[1] + `",` +
+		`"protected":"` + segments[0] + `",` +
+		`"header":{"kid":"e9bc097a-ce51-4036-9562-d2ade882db0d"},` +
+		`"signature":"` + segments[2] + `"}`
+}
+
+func es256TestKey() *ecdsa.PublicKey {
+	x, _ := decode(es256X)
+	y, _ := decode(es256Y)
+
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}
+}
+
+func TestParseJWSFlattened(t *testing.T) {
+	j, err := ParseJWS([]byte(es256FlattenedJWS()))
+
+	if err != nil {
+		t.Fatalf("Error while parsing: %v", err)
+	}
+
+	if len(j.Signatures) != 1 || j.Signatures[0].KeyID() != "e9bc097a-ce51-4036-9562-d2ade882db0d" {
+		t.Fatalf("Expected one signature with the kid from the unprotected header")
+	}
+
+	if _, err := j.Verify(ES256, es256TestKey()); err != nil {
+		t.Errorf("Error while verifying: %v", err)
+	}
+
+	if err := j.Signatures[0].Verify(ES384, es256TestKey()); err != ErrAlgorithmMismatch {
+		t.Errorf("Expected ErrAlgorithmMismatch but got %v", err)
+	}
+
+	payload, _ := j.Payload()
+
+	if !strings.HasPrefix(string(payload), `{"iss":"joe"`) {
+		t.Errorf("Unexpected payload %s", payload)
+	}
+
+	// the flattened form is reproduced exactly
+	flattened, _ := j.MarshalFlattened()
+
+	var got, want map[string]interface{}
+	json.Unmarshal(flattened, &got)
+	json.Unmarshal([]byte(es256FlattenedJWS()), &want)
+
+	if len(got) != len(want) || got["protected"] != want["protected"] || got["payload"] != want["payload"] || got["signature"] != want["signature"] {
+		t.Errorf("Expected %v but got %s", want, flattened)
+	}
+}
+
+func TestJWSRoundTrip(t *testing.T) {
+	rsaKey, _ := GenerateKey(RS256)
+	ecKey, _ := GenerateKey(ES256)
+
+	j := NewJWS([]byte("document"))
+
+	if _, err := j.Sign(RS256, rsaKey.PrivateKey, map[string]interface{}{"cty": "text/plain"}, map[string]interface{}{"kid": rsaKey.KeyID}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := j.Sign(ES256, ecKey.PrivateKey, nil, map[string]interface{}{"kid": ecKey.KeyID}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := j.MarshalFlattened(); err != ErrNotFlattened {
+		t.Errorf("Expected ErrNotFlattened but got %v", err)
+	}
+
+	data, err := json.Marshal(j)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parsed JWS
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("Error while parsing %s: %v", data, err)
+	}
+
+	if payload, _ := parsed.Payload(); string(payload) != "document" {
+		t.Errorf("Expected the payload document but got %s", payload)
+	}
+
+	if len(parsed.Signatures) != 2 || parsed.Signatures[0].Get("cty") != "text/plain" {
+		t.Fatalf("Expected two signatures with their headers but got %s", data)
+	}
+
+	if s, err := parsed.Verify(ES256, ecKey.PublicKey); err != nil || s.KeyID() != ecKey.KeyID {
+		t.Errorf("Expected the ES256 signature to verify (%v)", err)
+	}
+
+	if s, err := parsed.Verify(RS256, rsaKey.PublicKey); err != nil || s.KeyID() != rsaKey.KeyID {
+		t.Errorf("Expected the RS256 signature to verify (%v)", err)
+	}
+
+	if _, err := parsed.Verify(ES256, rsaKey.PublicKey); err != ErrBadSignature {
+		t.Errorf("Expected ErrBadSignature but got %v", err)
+	}
+
+	// changing the unprotected header doesn't affect the signature, changing
+	// the protected header does
+	parsed.Signatures[1].Header["kid"] = "changed"
+	parsed.Signatures[0].protected = encode([]byte(`{"alg":"RS256"}`))
+
+	if err := parsed.Signatures[1].Verify(ES256, ecKey.PublicKey); err != nil {
+		t.Errorf("Error while verifying: %v", err)
+	}
+
+	if err := parsed.Signatures[0].Verify(RS256, rsaKey.PublicKey); err == nil {
+		t.Errorf("Expected the modified protected header to fail verification")
+	}
+
+	if _, err := j.Sign(HS256, testKey, map[string]interface{}{"kid": "a"}, map[string]interface{}{"kid": "b"}); err != ErrDuplicateHeader {
+		t.Errorf("Expected ErrDuplicateHeader but got %v", err)
+	}
+}
+
+func TestParseJWSMalformed(t *testing.T) {
+	protected := encode([]byte(`{"alg":"HS256","kid":"a"}`))
+
+	tests := []struct {
+		data string
+		err  error
+	}{
+		{`[]`, ErrJWSMalformed},
+		{`{"signatures":[{"protected":"` + protected + `","signature":""}]}`, ErrJWSMalformed},
+		{`{"payload":"","signatures":[]}`, ErrNoSignatures},
+		{`{"payload":"","signatures":[{"protected":"` + protected + `"}]}`, ErrJWSMalformed},
+		{`{"payload":"","signatures":[{"signature":""}]}`, ErrJWSMalformed},
+		{`{"payload":"","signatures":[{"header":{"kid":"a"},"signature":""}]}`, ErrJWSMalformed},
+		{`{"payload":"","signatures":[{"protected":"` + protected + `","signature":""}],"signature":""}`, ErrJWSMalformed},
+		{`{"payload":"","protected":"` + protected + `","header":{"kid":"b"},"signature":""}`, ErrDuplicateHeader},
+		{`{"payload":"","protected":"` + protected + `","signature":"","signature":""}`, ErrJWSMalformed},
+		{`{"payload":"","protected":"not base64!","signature":""}`, ErrJWSMalformed},
+	}
+
+	for i, test := range tests {
+		if _, err := ParseJWS([]byte(test.data)); err != test.err {
+			t.Errorf("[%v] Expected %v but got %v", i, test.err, err)
+		}
+	}
+
+	// an unprotected "alg" is allowed
+	if _, err := ParseJWS([]byte(`{"payload":"","header":{"alg":"HS256"},"signature":""}`)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestJWSCrit(t *testing.T) {
+	j := NewJWS([]byte("document"))
+
+	s, _ := j.Sign(HS256, testKey, map[string]interface{}{"crit": []interface{}{"exp"}, "exp": 1}, nil)
+
+	data, _ := j.MarshalFlattened()
+	parsed, _ := ParseJWS(data)
+
+	for _, s := range []*Signature{s, parsed.Signatures[0]} {
+		if err := s.Verify(HS256, testKey); err == nil {
+			t.Errorf("Expected an unsupported critical parameter to be rejected")
+		}
+	}
+}
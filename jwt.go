@@ -1,8 +1,10 @@
 package jwt
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 )
@@ -15,12 +17,23 @@ var (
 	ErrHashUnavailable = errors.New("The hashing algorithm is not available")
 	ErrBadSignature    = errors.New("The signature doesn't match")
 	ErrTokenMalformed  = errors.New("The token is malformed")
+	ErrMissingClaim    = errors.New("The required claim is missing")
+	ErrInvalidTime     = errors.New("The claim is not a valid NumericDate")
+
+	ErrInvalidCritical     = errors.New("The crit header is invalid")
+	ErrUnsupportedCritical = errors.New("The critical header parameter is not supported")
 )
 
 const (
 	BadSignatureError ValidationError = 1 << iota
 	ExpiredError
 	NotYetValidError
+	MissingClaimError
+	InvalidClaimsError
+	LifetimeExceededError
+	TooOldError
+	InvalidTypeError
+	CriticalHeaderError
 )
 
 type ValidationError uint32
@@ -29,18 +42,91 @@ func (e ValidationError) Error() string {
 	return "the token is invalid"
 }
 
+// ClaimError describes why a single claim failed validation
+type ClaimError struct {
+	Claim string
+	Err   error
+}
+
+func (e *ClaimError) Error() string {
+	return fmt.Sprintf("claim %q: %v", e.Claim, e.Err)
+}
+
+func (e *ClaimError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError is returned by a Parser when a token fails validation and there
+// is more to say than the ValidationError flags. It wraps both the flags and
+// each individual failure so they can be inspected with errors.As and
+// errors.Is.
+type ParseError struct {
+	Flags  ValidationError
+	Errors []error
+}
+
+func (e *ParseError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+
+	return e.Flags.Error() + ": " + strings.Join(msgs, "; ")
+}
+
+func (e *ParseError) Unwrap() []error {
+	return append([]error{e.Flags}, e.Errors...)
+}
+
 type Token interface {
 	Encode(key interface{}) (payload string, err error)
 	Claim(string) interface{}
 	SetClaim(string, interface{})
 }
 
+// The interfaces below extend Token with optional features. Tokens created by
+// NewToken and returned by ParseToken implement all of them, so a Token from
+// this package may be asserted to any of them.
+
+// ContextToken is implemented by tokens that can pass a context through to
+// the signing algorithm when encoding
+type ContextToken interface {
+	Token
+	EncodeContext(ctx context.Context, key interface{}) (payload string, err error)
+}
+
+// TypedClaimsToken is implemented by tokens with typed claim accessors
+type TypedClaimsToken interface {
+	Token
+	ClaimString(string) (string, bool)
+	ClaimStrings(string) ([]string, bool)
+	ClaimBool(string) (bool, bool)
+	ClaimInt64(string) (int64, bool)
+	ClaimTime(string) (time.Time, bool)
+	ClaimMap(string) (map[string]interface{}, bool)
+}
+
+// HeaderToken is implemented by tokens that give access to their header
+type HeaderToken interface {
+	Token
+	Header(string) interface{}
+	SetHeader(string, interface{})
+}
+
+// VerifiedKeyToken is implemented by tokens that report which key in a
+// KeySet verified them
+type VerifiedKeyToken interface {
+	Token
+	VerifiedKeyID() string
+}
+
 type token struct {
 	raw       string
 	alg       SigningAlgorithm
 	header    map[string]interface{}
 	claims    map[string]interface{}
 	signature string
+	keyID     string
 }
 
 // NewToken creates a new token with the specified SigningAlgorithm
@@ -55,108 +141,109 @@ func NewToken(alg SigningAlgorithm) Token {
 	}
 }
 
+// ParseToken parses the token string and validates it using the given
+// SigningAlgorithm and key
 func ParseToken(tokenString string, alg SigningAlgorithm, key interface{}) (Token, error) {
-	segments := strings.Split(tokenString, ".")
-
-	if len(segments) != 3 {
-		return nil, ErrTokenMalformed
-	}
-
-	t := &token{
-		raw: tokenString,
-	}
+	return ParseContext(context.Background(), tokenString, alg, key)
+}
 
-	var (
-		headerBytes []byte
-		err         error
-	)
+// ParseContext is like ParseToken but passes ctx through to the signing
+// algorithm if it implements ContextSigningAlgorithm. If ctx is done before
+// the signature has been verified then the context's error is returned.
+func ParseContext(ctx context.Context, tokenString string, alg SigningAlgorithm, key interface{}) (Token, error) {
+	return defaultParser.ParseContext(ctx, tokenString, alg, key)
+}
 
-	if headerBytes, err = decode(segments[0]); err != nil {
-		return t, ErrTokenMalformed
-	}
+func (t *token) Claim(claim string) interface{} {
+	return t.claims[claim]
+}
 
-	if err = json.Unmarshal(headerBytes, &t.header); err != nil {
-		return t, ErrTokenMalformed
-	}
+func (t *token) SetClaim(claim string, v interface{}) {
+	t.claims[claim] = v
+}
 
-	var claimBytes []byte
-	if claimBytes, err = decode(segments[1]); err != nil {
-		return t, ErrTokenMalformed
-	}
+func (t *token) Header(name string) interface{} {
+	return t.header[name]
+}
 
-	if err = json.Unmarshal(claimBytes, &t.claims); err != nil {
-		return t, ErrTokenMalformed
+// SetHeader sets a header parameter, for example "typ" to use explicit typing
+// such as "at+jwt". Setting a parameter to nil removes it. The "alg"
+// parameter is set from the SigningAlgorithm by NewToken and shouldn't be
+// changed.
+func (t *token) SetHeader(name string, v interface{}) {
+	if v == nil {
+		delete(t.header, name)
+		return
 	}
 
-	var errs ValidationError
+	t.header[name] = v
+}
 
-	// check sig
-	if err = alg.Verify(strings.Join(segments[0:2], "."), segments[2], key); err != nil {
-		errs |= BadSignatureError
-	}
+// VerifiedKeyID returns the ID of the key in the KeySet that verified the
+// token's signature. It's empty if the token wasn't parsed with a KeySet or
+// no key verified it.
+func (t *token) VerifiedKeyID() string {
+	return t.keyID
+}
 
-	// check exp
-	now := TimeFunc().Unix()
+func (t *token) Encode(key interface{}) (payload string, err error) {
+	return t.EncodeContext(context.Background(), key)
+}
 
-	if exp, ok := t.claims["exp"].(float64); ok {
-		if now > int64(exp) {
-			errs |= ExpiredError
-		}
-	}
+// EncodeContext is like Encode but passes ctx through to the signing
+// algorithm if it implements ContextSigningAlgorithm.
+func (t *token) EncodeContext(ctx context.Context, key interface{}) (payload string, err error) {
+	var buf, sig []byte
 
-	if nbf, ok := t.claims["nbf"].(float64); ok {
-		if now < int64(nbf) {
-			errs |= NotYetValidError
-		}
+	if buf, err = t.appendPayload(make([]byte, 0, 256)); err != nil {
+		return
 	}
 
-	if errs == 0 {
-		return t, nil
+	if sig, err = signContext(ctx, t.alg, buf, key); err != nil {
+		return
 	}
 
-	return t, errs
+	buf = append(buf, '.')
+	buf = appendEncode(buf, sig)
 
+	return string(buf), nil
 }
 
-func (t *token) Claim(claim string) interface{} {
-	return t.claims[claim]
-}
-
-func (t *token) SetClaim(claim string, v interface{}) {
-	t.claims[claim] = v
-}
-
-func (t *token) Encode(key interface{}) (payload string, err error) {
-	var sig string
-
-	if payload, err = t.payload(); err != nil {
-		return
+// encodeContext encodes the token using EncodeContext if it supports it and
+// Encode otherwise
+func encodeContext(ctx context.Context, t Token, key interface{}) (string, error) {
+	if ctxToken, ok := t.(ContextToken); ok {
+		return ctxToken.EncodeContext(ctx, key)
 	}
 
-	if sig, err = t.alg.Sign(payload, key); err != nil {
-		return
+	if err := ctx.Err(); err != nil {
+		return "", err
 	}
 
-	payload += "." + sig
-
-	return
+	return t.Encode(key)
 }
 
-func (t *token) payload() (payload string, err error) {
-	var jsonValue []byte
+// appendPayload appends the encoded header and claims, which form the
+// signing input, to buf
+func (t *token) appendPayload(buf []byte) ([]byte, error) {
+	var (
+		jsonValue []byte
+		err       error
+	)
 
 	// lets do the header
 	if jsonValue, err = json.Marshal(t.header); err != nil {
-		return
+		return nil, err
 	}
 
-	payload = encode(jsonValue)
+	buf = appendEncode(buf, jsonValue)
 
 	if jsonValue, err = json.Marshal(t.claims); err != nil {
-		return
+		return nil, err
 	}
 
-	payload += "." + encode(jsonValue)
+	buf = append(buf, '.')
+	buf = appendEncode(buf, jsonValue)
 
-	return
+	return buf, nil
 }
@@ -0,0 +1,426 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Errors relating to managed keys
+var (
+	ErrNoSigningKey    = errors.New("There is no active signing key")
+	ErrInvalidKeyID    = errors.New("The key ID is empty, invalid or already in use")
+	ErrKeyNotFound     = errors.New("The key was not found")
+	ErrMissingKeyData  = errors.New("The managed key has no key")
+	ErrTokenHeader     = errors.New("The token doesn't give access to its header")
+	ErrInvalidSchedule = errors.New("The keys would retire before the new key is promoted")
+)
+
+// algorithms are the algorithms that managed keys may be used with, by name
+var algorithms = map[string]SigningAlgorithm{
+	"HS256": HS256, "HS384": HS384, "HS512": HS512,
+	"RS256": RS256, "RS384": RS384, "RS512": RS512,
+	"ES256": ES256, "ES384": ES384, "ES512": ES512,
+	"EdDSA": EdDSA,
+}
+
+// ManagedKey is a key held by a KeyManager. A key is used to verify tokens
+// until RetireAt, and is used to sign them from SignFrom until it's replaced
+// by a key with a later SignFrom. A key with a zero SignFrom is only used for
+// verification, and a key with a zero RetireAt is never retired.
+type ManagedKey struct {
+	ID        string
+	Algorithm SigningAlgorithm
+
+	// PrivateKey signs tokens and is nil for verification-only keys. For the
+	// HMAC algorithms it holds the secret and PublicKey is nil. JWKSet never
+	// publishes a secret, even if it's held in PublicKey.
+	PrivateKey crypto.PrivateKey
+	PublicKey  crypto.PublicKey
+
+	SignFrom time.Time
+	RetireAt time.Time
+}
+
+// ManagedKey returns the generated key as a ManagedKey with the given
+// schedule
+func (k *GeneratedKey) ManagedKey(signFrom time.Time, retireAt time.Time) *ManagedKey {
+	return &ManagedKey{
+		ID:         k.KeyID,
+		Algorithm:  k.Algorithm,
+		PrivateKey: k.PrivateKey,
+		PublicKey:  k.PublicKey,
+		SignFrom:   signFrom,
+		RetireAt:   retireAt,
+	}
+}
+
+// verificationKey returns the key used to verify tokens
+func (k *ManagedKey) verificationKey() interface{} {
+	if k.PublicKey != nil {
+		return k.PublicKey
+	}
+
+	return k.PrivateKey
+}
+
+// retired reports whether the key is retired at now
+func (k *ManagedKey) retired(now time.Time) bool {
+	return !k.RetireAt.IsZero() && !now.Before(k.RetireAt)
+}
+
+// signing reports whether the key may sign at now
+func (k *ManagedKey) signing(now time.Time) bool {
+	return k.PrivateKey != nil && !k.SignFrom.IsZero() && !now.Before(k.SignFrom) && !k.retired(now)
+}
+
+// KeyManager holds a set of keys with schedules for promoting them to the
+// signing key and retiring them. It chooses the key used by Encode, and
+// provides the KeySet for parsing and the JWKSet to publish. The current
+// time is taken from TimeFunc. A KeyManager is safe for concurrent use.
+type KeyManager struct {
+	mu    sync.RWMutex
+	keys  []*ManagedKey
+	store KeyStore
+}
+
+// NewKeyManager creates a KeyManager. If store isn't nil the keys are loaded
+// from it and changes are saved to it.
+func NewKeyManager(store KeyStore) (*KeyManager, error) {
+	m := &KeyManager{store: store}
+
+	if store == nil {
+		return m, nil
+	}
+
+	keys, err := store.Load()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		if err := m.add(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// add adds a copy of the key without saving
+func (m *KeyManager) add(key *ManagedKey) error {
+	if key.ID == "" {
+		return ErrInvalidKeyID
+	}
+
+	for _, k := range m.keys {
+		if k.ID == key.ID {
+			return ErrInvalidKeyID
+		}
+	}
+
+	if key.Algorithm == nil {
+		return ErrUnsupportedAlgorithm
+	}
+
+	if key.PrivateKey == nil && key.PublicKey == nil {
+		return ErrMissingKeyData
+	}
+
+	k := *key
+
+	if signer, ok := k.PrivateKey.(crypto.Signer); ok && k.PublicKey == nil {
+		k.PublicKey = signer.Public()
+	}
+
+	if err := checkKey(k.Algorithm, k.verificationKey()); err != nil {
+		return err
+	}
+
+	m.keys = append(m.keys, &k)
+
+	return nil
+}
+
+// save saves the keys to the store, if there is one. It must be called with
+// the lock held.
+func (m *KeyManager) save() error {
+	if m.store == nil {
+		return nil
+	}
+
+	return m.store.Save(m.copyKeys())
+}
+
+// copyKeys returns copies of the keys. It must be called with the lock held.
+func (m *KeyManager) copyKeys() []*ManagedKey {
+	keys := make([]*ManagedKey, len(m.keys))
+
+	for i, key := range m.keys {
+		k := *key
+		keys[i] = &k
+	}
+
+	return keys
+}
+
+// Add adds a copy of the key to the manager. The key's ID must be unique. If
+// the key can't be saved it isn't added.
+func (m *KeyManager) Add(key *ManagedKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.add(key); err != nil {
+		return err
+	}
+
+	if err := m.save(); err != nil {
+		m.keys = m.keys[:len(m.keys)-1]
+		return err
+	}
+
+	return nil
+}
+
+// Remove removes the key with the given ID
+func (m *KeyManager) Remove(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, key := range m.keys {
+		if key.ID == id {
+			previous := m.keys
+			m.keys = append(m.keys[:i:i], m.keys[i+1:]...)
+
+			if err := m.save(); err != nil {
+				m.keys = previous
+				return err
+			}
+
+			return nil
+		}
+	}
+
+	return ErrKeyNotFound
+}
+
+// Keys returns copies of all the keys, including retired keys
+func (m *KeyManager) Keys() []*ManagedKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.copyKeys()
+}
+
+// Prune removes the keys that have been retired
+func (m *KeyManager) Prune() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := TimeFunc()
+	keys := make([]*ManagedKey, 0, len(m.keys))
+
+	for _, key := range m.keys {
+		if !key.retired(now) {
+			keys = append(keys, key)
+		}
+	}
+
+	if len(keys) == len(m.keys) {
+		return nil
+	}
+
+	previous := m.keys
+	m.keys = keys
+
+	if err := m.save(); err != nil {
+		m.keys = previous
+		return err
+	}
+
+	return nil
+}
+
+// Rotate generates a new key for alg that becomes the signing key at
+// promoteAt, and schedules the keys that are currently able to sign to retire
+// at retireAt, unless it's zero. retireAt should leave time for tokens signed
+// by the old keys to expire. The new key is published by JWKSet straight away so that
+// verifiers can fetch it before it's used. ErrInvalidSchedule is returned if
+// retireAt isn't after promoteAt, as there would be no key to sign with in
+// between.
+func (m *KeyManager) Rotate(alg SigningAlgorithm, promoteAt time.Time, retireAt time.Time) (*ManagedKey, error) {
+	if !retireAt.IsZero() && !retireAt.After(promoteAt) {
+		return nil, ErrInvalidSchedule
+	}
+
+	generated, err := GenerateKey(alg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := TimeFunc()
+	previous := m.keys
+
+	// the keys are copied so that a failed save can be undone
+	m.keys = m.copyKeys()
+
+	for _, key := range m.keys {
+		if key.signing(now) && !retireAt.IsZero() && (key.RetireAt.IsZero() || key.RetireAt.After(retireAt)) {
+			key.RetireAt = retireAt
+		}
+	}
+
+	key := generated.ManagedKey(promoteAt, time.Time{})
+
+	if err := m.add(key); err != nil {
+		m.keys = previous
+		return nil, err
+	}
+
+	if err := m.save(); err != nil {
+		m.keys = previous
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// SigningKey returns a copy of the key currently used for signing, which is
+// the key with the latest SignFrom that isn't in the future
+func (m *KeyManager) SigningKey() (*ManagedKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := TimeFunc()
+
+	var signing *ManagedKey
+	for _, key := range m.keys {
+		if key.signing(now) && (signing == nil || key.SignFrom.After(signing.SignFrom)) {
+			signing = key
+		}
+	}
+
+	if signing == nil {
+		return nil, ErrNoSigningKey
+	}
+
+	k := *signing
+
+	return &k, nil
+}
+
+// NewToken creates a new token for the algorithm of the current signing key
+func (m *KeyManager) NewToken() (Token, error) {
+	key, err := m.SigningKey()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewToken(key.Algorithm), nil
+}
+
+// Encode signs the token with the current signing key and sets its "kid"
+// header to the key's ID. The token must implement HeaderToken and its
+// algorithm must match the key's, use NewToken to create a token with the
+// right algorithm.
+func (m *KeyManager) Encode(t Token) (string, error) {
+	return m.EncodeContext(context.Background(), t)
+}
+
+// EncodeContext is like Encode but passes ctx through to the signing
+// algorithm
+func (m *KeyManager) EncodeContext(ctx context.Context, t Token) (string, error) {
+	key, err := m.SigningKey()
+
+	if err != nil {
+		return "", err
+	}
+
+	headerToken, ok := t.(HeaderToken)
+
+	if !ok {
+		return "", ErrTokenHeader
+	}
+
+	if headerToken.Header("alg") != key.Algorithm.Name() {
+		return "", ErrAlgorithmMismatch
+	}
+
+	headerToken.SetHeader("kid", key.ID)
+
+	return encodeContext(ctx, t, key.PrivateKey)
+}
+
+// KeySet returns a KeySet holding the keys that haven't been retired, each
+// bound to its algorithm, for passing to Parse with a nil algorithm. The
+// KeySet is a snapshot so should be fetched again for each token, or at
+// least regularly, to pick up changes.
+func (m *KeyManager) KeySet() *KeySet {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := TimeFunc()
+	set := NewKeySet()
+
+	for _, key := range m.keys {
+		if !key.retired(now) {
+			// the key was checked when it was added so this can't fail
+			_ = set.AddWithAlgorithm(key.ID, key.Algorithm, key.verificationKey())
+		}
+	}
+
+	return set
+}
+
+// JWKSet returns the public keys of the keys that haven't been retired, with
+// their "kid", "alg" and "use" set. HMAC keys are never included. The keys
+// are sorted by ID so that the result is stable.
+func (m *KeyManager) JWKSet() (*JWKSet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := TimeFunc()
+	set := &JWKSet{Keys: []*JWK{}}
+
+	for _, key := range m.keys {
+		if key.retired(now) || key.PublicKey == nil {
+			continue
+		}
+
+		if _, ok := key.Algorithm.(*SigningAlgorithmHMAC); ok {
+			continue
+		}
+
+		jwk, err := NewJWK(key.PublicKey)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// PublicKey may hold a secret or a private key by mistake
+		if jwk = jwk.Public(); jwk == nil {
+			continue
+		}
+
+		jwk.KeyID = key.ID
+		jwk.Algorithm = key.Algorithm.Name()
+		jwk.Use = "sig"
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].KeyID < set.Keys[j].KeyID
+	})
+
+	return set, nil
+}
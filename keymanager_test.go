@@ -0,0 +1,223 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyManagerRotation(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	defer func() { TimeFunc = time.Now }()
+	TimeFunc = func() time.Time { return now }
+
+	m, _ := NewKeyManager(nil)
+
+	if _, err := m.NewToken(); err != ErrNoSigningKey {
+		t.Errorf("Expected ErrNoSigningKey but got %v", err)
+	}
+
+	first, err := m.Rotate(ES256, now, time.Time{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tok, _ := m.NewToken()
+	oldToken, err := m.Encode(tok)
+
+	if err != nil {
+		t.Fatalf("Error while signing: %v", err)
+	}
+
+	if kid := tok.(HeaderToken).Header("kid"); kid != first.ID {
+		t.Errorf("Expected kid %v but got %v", first.ID, kid)
+	}
+
+	// the new key is published straight away but isn't used to sign until
+	// it's promoted
+	second, err := m.Rotate(EdDSA, now.Add(24*time.Hour), now.Add(48*time.Hour))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if key, _ := m.SigningKey(); key.ID != first.ID {
+		t.Errorf("Expected %v to still be the signing key but got %v", first.ID, key.ID)
+	}
+
+	if set, _ := m.JWKSet(); len(set.Keys) != 2 || set.Key(second.ID) == nil {
+		t.Errorf("Expected both keys to be published but got %v", set.Keys)
+	}
+
+	now = now.Add(24 * time.Hour)
+
+	if key, _ := m.SigningKey(); key.ID != second.ID {
+		t.Errorf("Expected %v to be the signing key but got %v", second.ID, key.ID)
+	}
+
+	// an ES256 token can't be signed with the EdDSA key
+	if _, err := m.Encode(tok); err != ErrAlgorithmMismatch {
+		t.Errorf("Expected ErrAlgorithmMismatch but got %v", err)
+	}
+
+	tok, _ = m.NewToken()
+	newToken, _ := m.Encode(tok)
+
+	for _, test := range []struct {
+		token string
+		kid   string
+	}{
+		{oldToken, first.ID},
+		{newToken, second.ID},
+	} {
+		parsed, err := ParseToken(test.token, nil, m.KeySet())
+
+		if err != nil {
+			t.Errorf("[%v] Error while verifying: %v", test.kid, err)
+		} else if id := parsed.(VerifiedKeyToken).VerifiedKeyID(); id != test.kid {
+			t.Errorf("[%v] Expected the token to be verified by %v but got %v", test.kid, test.kid, id)
+		}
+	}
+
+	// once the old key retires its tokens are rejected
+	now = now.Add(24 * time.Hour)
+
+	if _, err := ParseToken(oldToken, nil, m.KeySet()); err != BadSignatureError {
+		t.Errorf("Expected BadSignatureError but got %v", err)
+	}
+
+	if set, _ := m.JWKSet(); len(set.Keys) != 1 || set.Keys[0].KeyID != second.ID {
+		t.Errorf("Expected only %v to be published but got %v", second.ID, set.Keys)
+	}
+
+	if err := m.Prune(); err != nil || len(m.Keys()) != 1 {
+		t.Errorf("Expected the retired key to be pruned (%v)", err)
+	}
+}
+
+func TestKeyManagerRotateSchedule(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	defer func() { TimeFunc = time.Now }()
+	TimeFunc = func() time.Time { return now }
+
+	m, _ := NewKeyManager(nil)
+
+	if _, err := m.Rotate(ES256, now, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, retireAt := range []time.Time{now, now.Add(12 * time.Hour), now.Add(24 * time.Hour)} {
+		if _, err := m.Rotate(ES256, now.Add(24*time.Hour), retireAt); err != ErrInvalidSchedule {
+			t.Errorf("[%v] Expected ErrInvalidSchedule but got %v", retireAt, err)
+		}
+	}
+
+	keys := m.Keys()
+
+	if len(keys) != 1 || !keys[0].RetireAt.IsZero() {
+		t.Errorf("Expected the keys to be unchanged but got %v", keys)
+	}
+}
+
+func TestKeyManagerHMAC(t *testing.T) {
+	m, _ := NewKeyManager(nil)
+
+	if err := m.Add(&ManagedKey{ID: "secret", Algorithm: HS256, PrivateKey: []byte(testKey), SignFrom: time.Unix(0, 0)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Add(&ManagedKey{ID: "secret", Algorithm: HS256, PrivateKey: []byte(testKey)}); err != ErrInvalidKeyID {
+		t.Errorf("Expected ErrInvalidKeyID for a duplicate ID but got %v", err)
+	}
+
+	tok, _ := m.NewToken()
+	encoded, err := m.Encode(tok)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ParseToken(encoded, nil, m.KeySet()); err != nil {
+		t.Errorf("Error while verifying: %v", err)
+	}
+
+	// an RS256 parser can't be tricked into using the secret
+	if _, err := ParseToken(encoded, RS256, m.KeySet()); err != BadSignatureError {
+		t.Errorf("Expected BadSignatureError but got %v", err)
+	}
+
+	if set, _ := m.JWKSet(); len(set.Keys) != 0 {
+		t.Errorf("Expected the secret not to be published but got %v", set.Keys)
+	}
+}
+
+func TestKeyManagerVerificationOnly(t *testing.T) {
+	partner, _ := GenerateKey(RS256)
+
+	m, _ := NewKeyManager(nil)
+	m.Add(&ManagedKey{ID: "partner", Algorithm: RS256, PublicKey: partner.PublicKey})
+
+	if _, err := m.SigningKey(); err != ErrNoSigningKey {
+		t.Errorf("Expected ErrNoSigningKey but got %v", err)
+	}
+
+	tok := NewToken(RS256)
+	encoded, _ := tok.Encode(partner.PrivateKey)
+
+	if parsed, err := ParseToken(encoded, nil, m.KeySet()); err != nil || parsed.(VerifiedKeyToken).VerifiedKeyID() != "partner" {
+		t.Errorf("Expected the partner key to verify the token (%v)", err)
+	}
+
+	// the token's alg header must match the key's algorithm
+	forged := signToken(t, `{"alg":"HS256"}`, `{}`)
+
+	if _, err := ParseToken(forged, nil, m.KeySet()); err != BadSignatureError {
+		t.Errorf("Expected BadSignatureError but got %v", err)
+	}
+}
+
+func TestKeyManagerJWKSetSecrets(t *testing.T) {
+	partner, _ := GenerateKey(ES256)
+
+	m, _ := NewKeyManager(nil)
+
+	for _, key := range []*ManagedKey{
+		{ID: "secret", Algorithm: HS256, PublicKey: []byte(testKey)},
+		{ID: "private", Algorithm: ES256, PublicKey: partner.PrivateKey},
+	} {
+		if err := m.Add(key); err != nil {
+			t.Fatalf("[%v] Error while adding the key: %v", key.ID, err)
+		}
+	}
+
+	set, err := m.JWKSet()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(set.Keys) != 1 || set.Keys[0].KeyID != "private" || set.Keys[0].IsPrivate() {
+		t.Errorf("Expected only the public part of the ES256 key but got %v", set.Keys)
+	}
+}
+
+// basicToken only implements Token
+type basicToken struct {
+	Token
+}
+
+func TestKeyManagerTokenHeader(t *testing.T) {
+	m, _ := NewKeyManager(nil)
+
+	if _, err := m.Rotate(HS256, TimeFunc(), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	tok, _ := m.NewToken()
+
+	if _, err := m.Encode(basicToken{tok}); err != ErrTokenHeader {
+		t.Errorf("Expected ErrTokenHeader but got %v", err)
+	}
+}
@@ -0,0 +1,578 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/pbkdf2"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"hash"
+	"math/big"
+)
+
+// MaxPBKDF2Iterations is the largest PBKDF2 iteration count accepted for an
+// encrypted PKCS8 key. The count is read from the key itself, so without a
+// limit a crafted key could take arbitrarily long to derive. OpenSSL uses
+// 2048 by default.
+var MaxPBKDF2Iterations = 1000000
+
+// Errors relating to parsing keys
+var (
+	ErrUnsupportedKeyFormat = errors.New("The key format is not supported")
+	ErrPassphraseRequired   = errors.New("The key is encrypted and requires a passphrase")
+	ErrIncorrectPassphrase  = errors.New("The passphrase is incorrect or the key is corrupt")
+	ErrTooManyIterations    = errors.New("The key's PBKDF2 iteration count exceeds MaxPBKDF2Iterations")
+	ErrEncryptedOpenSSHKey  = errors.New("Encrypted OpenSSH private keys are not supported, decrypt the key with ssh-keygen -p first")
+)
+
+// ParsePrivateKey parses a private key and returns an *rsa.PrivateKey, an
+// *ecdsa.PrivateKey or an ed25519.PrivateKey. The key may be PEM encoded
+// PKCS1, SEC1, PKCS8 or OpenSSH, or DER encoded PKCS1, SEC1 or PKCS8 without
+// PEM armor. Use ParsePrivateKeyWithPassphrase for encrypted keys.
+func ParsePrivateKey(data []byte) (crypto.PrivateKey, error) {
+	return ParsePrivateKeyWithPassphrase(data, nil)
+}
+
+// ParsePrivateKeyWithPassphrase is like ParsePrivateKey but also accepts
+// passphrase protected PKCS8 keys encrypted with PBES2, using PBKDF2 with at
+// most MaxPBKDF2Iterations iterations. Encrypted OpenSSH keys aren't
+// supported and return ErrEncryptedOpenSSHKey, since they use bcrypt_pbkdf
+// which the standard library doesn't provide. Remove their passphrase with
+// ssh-keygen -p, or convert them to PKCS8 with ssh-keygen -p -m PKCS8.
+func ParsePrivateKeyWithPassphrase(data []byte, passphrase []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+
+	if block == nil {
+		// DER without PEM armor
+		if key, err := parsePrivateKeyDER(data); err == nil {
+			return key, nil
+		}
+
+		return decryptPKCS8(data, passphrase)
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "ENCRYPTED PRIVATE KEY":
+		return decryptPKCS8(block.Bytes, passphrase)
+	case "OPENSSH PRIVATE KEY":
+		return parseOpenSSHPrivateKey(block.Bytes)
+	}
+
+	return parsePrivateKeyDER(block.Bytes)
+}
+
+// ParsePublicKey parses a public key and returns an *rsa.PublicKey, an
+// *ecdsa.PublicKey or an ed25519.PublicKey. The key may be PEM or DER encoded
+// PKIX, PKCS1 or an X.509 certificate, or a line in the OpenSSH
+// authorized_keys format. If data holds an unencrypted private key then its
+// public key is returned.
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	if key, err := parseAuthorizedKey(data); err == nil {
+		return key, nil
+	}
+
+	der := data
+	if block, _ := pem.Decode(data); block != nil {
+		der = block.Bytes
+	}
+
+	if key, err := x509.ParsePKIXPublicKey(der); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(der); err == nil {
+		return key, nil
+	}
+
+	if cert, err := x509.ParseCertificate(der); err == nil {
+		return cert.PublicKey, nil
+	}
+
+	if key, err := ParsePrivateKey(data); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer.Public(), nil
+		}
+	}
+
+	return nil, ErrUnsupportedKeyFormat
+}
+
+// parsePrivateKeyDER tries each of the DER encodings of a private key
+func parsePrivateKeyDER(der []byte) (crypto.PrivateKey, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	return nil, ErrUnsupportedKeyFormat
+}
+
+// Object identifiers used by PKCS5 PBES2
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC     = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+)
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// decryptPKCS8 decrypts a DER encoded PKCS8 EncryptedPrivateKeyInfo that uses
+// PBES2 with PBKDF2 and an AES or triple DES CBC cipher
+func decryptPKCS8(der []byte, passphrase []byte) (crypto.PrivateKey, error) {
+	var info encryptedPrivateKeyInfo
+	if rest, err := asn1.Unmarshal(der, &info); err != nil || len(rest) > 0 {
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	if passphrase == nil {
+		return nil, ErrPassphraseRequired
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	var prf func() hash.Hash
+	switch oid := kdf.PRF.Algorithm; {
+	case len(oid) == 0, oid.Equal(oidHMACWithSHA1):
+		prf = sha1.New
+	case oid.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	case oid.Equal(oidHMACWithSHA384):
+		prf = sha512.New384
+	case oid.Equal(oidHMACWithSHA512):
+		prf = sha512.New
+	default:
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	var (
+		newCipher func(key []byte) (cipher.Block, error)
+		keyLen    int
+	)
+
+	switch oid := params.EncryptionScheme.Algorithm; {
+	case oid.Equal(oidAES128CBC):
+		newCipher, keyLen = aes.NewCipher, 16
+	case oid.Equal(oidAES192CBC):
+		newCipher, keyLen = aes.NewCipher, 24
+	case oid.Equal(oidAES256CBC):
+		newCipher, keyLen = aes.NewCipher, 32
+	case oid.Equal(oidDESEDE3CBC):
+		newCipher, keyLen = des.NewTripleDESCipher, 24
+	default:
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	if kdf.IterationCount < 1 || (kdf.KeyLength != 0 && kdf.KeyLength != keyLen) {
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	if kdf.IterationCount > MaxPBKDF2Iterations {
+		return nil, ErrTooManyIterations
+	}
+
+	derived, err := pbkdf2.Key(prf, string(passphrase), kdf.Salt, kdf.IterationCount, keyLen)
+
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := newCipher(derived)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data := info.EncryptedData
+	if len(iv) != block.BlockSize() || len(data) == 0 || len(data)%block.BlockSize() != 0 {
+		return nil, ErrIncorrectPassphrase
+	}
+
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+
+	// remove and check the PKCS7 padding
+	pad := int(plain[len(plain)-1])
+	if pad == 0 || pad > block.BlockSize() {
+		return nil, ErrIncorrectPassphrase
+	}
+
+	for _, b := range plain[len(plain)-pad:] {
+		if int(b) != pad {
+			return nil, ErrIncorrectPassphrase
+		}
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(plain[:len(plain)-pad])
+
+	if err != nil {
+		return nil, ErrIncorrectPassphrase
+	}
+
+	return key, nil
+}
+
+// sshReader reads the fields of the SSH wire format described in RFC 4251
+type sshReader struct {
+	data []byte
+	err  error
+}
+
+func (r *sshReader) uint32() uint32 {
+	if r.err != nil || len(r.data) < 4 {
+		r.err = ErrUnsupportedKeyFormat
+		return 0
+	}
+
+	v := binary.BigEndian.Uint32(r.data)
+	r.data = r.data[4:]
+
+	return v
+}
+
+func (r *sshReader) bytes() []byte {
+	n := r.uint32()
+
+	if r.err != nil || uint32(len(r.data)) < n {
+		r.err = ErrUnsupportedKeyFormat
+		return nil
+	}
+
+	v := r.data[:n]
+	r.data = r.data[n:]
+
+	return v
+}
+
+func (r *sshReader) string() string {
+	return string(r.bytes())
+}
+
+func (r *sshReader) mpint() *big.Int {
+	b := r.bytes()
+
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		// negative numbers never appear in keys
+		r.err = ErrUnsupportedKeyFormat
+	}
+
+	return new(big.Int).SetBytes(b)
+}
+
+// sshCurves maps the curve names used by OpenSSH to the curves
+var sshCurves = map[string]struct {
+	curve elliptic.Curve
+	ecdh  ecdh.Curve
+}{
+	"nistp256": {elliptic.P256(), ecdh.P256()},
+	"nistp384": {elliptic.P384(), ecdh.P384()},
+	"nistp521": {elliptic.P521(), ecdh.P521()},
+}
+
+// parseSSHECPoint parses an uncompressed point on the named curve
+func parseSSHECPoint(curveName string, point []byte) (*ecdsa.PublicKey, error) {
+	curve, ok := sshCurves[curveName]
+
+	if !ok {
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	// crypto/ecdh checks the point is on the curve
+	if _, err := curve.ecdh.NewPublicKey(point); err != nil {
+		return nil, err
+	}
+
+	size := (len(point) - 1) / 2
+
+	return &ecdsa.PublicKey{
+		Curve: curve.curve,
+		X:     new(big.Int).SetBytes(point[1 : 1+size]),
+		Y:     new(big.Int).SetBytes(point[1+size:]),
+	}, nil
+}
+
+// parseSSHECPrivateKey checks that the private scalar d matches the
+// uncompressed point on the named curve
+func parseSSHECPrivateKey(curveName string, point []byte, d *big.Int) (*ecdsa.PrivateKey, error) {
+	public, err := parseSSHECPoint(curveName, point)
+
+	if err != nil {
+		return nil, err
+	}
+
+	size := (public.Curve.Params().BitSize + 7) / 8
+	if d.BitLen() > 8*size {
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	ecdhKey, err := sshCurves[curveName].ecdh.NewPrivateKey(d.FillBytes(make([]byte, size)))
+
+	if err != nil || !bytes.Equal(ecdhKey.PublicKey().Bytes(), point) {
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	return &ecdsa.PrivateKey{PublicKey: *public, D: d}, nil
+}
+
+// parseSSHPublicKey parses a public key in the SSH wire format
+func parseSSHPublicKey(data []byte) (crypto.PublicKey, error) {
+	r := &sshReader{data: data}
+
+	var key crypto.PublicKey
+
+	switch keyType := r.string(); keyType {
+	case "ssh-rsa":
+		e := r.mpint()
+		n := r.mpint()
+
+		if r.err == nil && !e.IsInt64() {
+			return nil, ErrUnsupportedKeyFormat
+		}
+
+		key = &rsa.PublicKey{N: n, E: int(e.Int64())}
+	case "ssh-ed25519":
+		pub := r.bytes()
+
+		if r.err == nil && len(pub) != ed25519.PublicKeySize {
+			return nil, ErrUnsupportedKeyFormat
+		}
+
+		key = ed25519.PublicKey(pub)
+	case "ecdsa-sha2-nistp256", "ecdsa-sha2-nistp384", "ecdsa-sha2-nistp521":
+		curveName := r.string()
+		point := r.bytes()
+
+		if r.err == nil {
+			if keyType[len("ecdsa-sha2-"):] != curveName {
+				return nil, ErrUnsupportedKeyFormat
+			}
+
+			var err error
+			if key, err = parseSSHECPoint(curveName, point); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	if r.err != nil || len(r.data) > 0 {
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	return key, nil
+}
+
+// parseAuthorizedKey parses a public key in the OpenSSH authorized_keys
+// format, for example "ssh-ed25519 AAAA... comment". Options before the key
+// type aren't supported.
+func parseAuthorizedKey(data []byte) (crypto.PublicKey, error) {
+	fields := bytes.Fields(data)
+
+	if len(fields) < 2 || !bytes.HasPrefix(fields[0], []byte("ssh-")) && !bytes.HasPrefix(fields[0], []byte("ecdsa-")) {
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	wire := make([]byte, base64.StdEncoding.DecodedLen(len(fields[1])))
+	n, err := base64.StdEncoding.Decode(wire, fields[1])
+
+	if err != nil {
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	// the key type is repeated inside the encoded key
+	if r := (&sshReader{data: wire[:n]}); r.string() != string(fields[0]) {
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	return parseSSHPublicKey(wire[:n])
+}
+
+// parseOpenSSHPrivateKey parses the body of an "OPENSSH PRIVATE KEY" PEM
+// block, as described in PROTOCOL.key in the OpenSSH sources
+func parseOpenSSHPrivateKey(data []byte) (crypto.PrivateKey, error) {
+	const magic = "openssh-key-v1\x00"
+
+	if !bytes.HasPrefix(data, []byte(magic)) {
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	r := &sshReader{data: data[len(magic):]}
+
+	cipherName := r.string()
+	kdfName := r.string()
+	r.bytes() // kdf options
+	count := r.uint32()
+	publicKey := r.bytes()
+	private := r.bytes()
+
+	if r.err != nil || count != 1 {
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	if cipherName != "none" || kdfName != "none" {
+		return nil, ErrEncryptedOpenSSHKey
+	}
+
+	r = &sshReader{data: private}
+
+	// the check ints match when the key was decrypted correctly
+	if r.uint32() != r.uint32() {
+		return nil, ErrIncorrectPassphrase
+	}
+
+	var (
+		key crypto.PrivateKey
+		pub crypto.PublicKey
+	)
+
+	switch keyType := r.string(); keyType {
+	case "ssh-rsa":
+		n := r.mpint()
+		e := r.mpint()
+		d := r.mpint()
+		r.mpint() // iqmp, recomputed by Precompute
+		p := r.mpint()
+		q := r.mpint()
+
+		if r.err != nil || !e.IsInt64() {
+			return nil, ErrUnsupportedKeyFormat
+		}
+
+		rsaKey := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{N: n, E: int(e.Int64())},
+			D:         d,
+			Primes:    []*big.Int{p, q},
+		}
+
+		if err := rsaKey.Validate(); err != nil {
+			return nil, err
+		}
+
+		rsaKey.Precompute()
+		key, pub = rsaKey, &rsaKey.PublicKey
+	case "ssh-ed25519":
+		r.bytes() // public key, repeated at the end of the private key
+		priv := r.bytes()
+
+		if r.err != nil || len(priv) != ed25519.PrivateKeySize {
+			return nil, ErrUnsupportedKeyFormat
+		}
+
+		edKey := ed25519.NewKeyFromSeed(priv[:ed25519.SeedSize])
+
+		if !bytes.Equal(edKey, priv) {
+			return nil, ErrUnsupportedKeyFormat
+		}
+
+		key, pub = edKey, edKey.Public()
+	case "ecdsa-sha2-nistp256", "ecdsa-sha2-nistp384", "ecdsa-sha2-nistp521":
+		curveName := r.string()
+		point := r.bytes()
+		d := r.mpint()
+
+		if r.err != nil {
+			return nil, ErrUnsupportedKeyFormat
+		}
+
+		ecKey, err := parseSSHECPrivateKey(curveName, point, d)
+
+		if err != nil {
+			return nil, err
+		}
+
+		key, pub = ecKey, &ecKey.PublicKey
+	default:
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	r.bytes() // comment
+
+	if r.err != nil {
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	// the unencrypted public key must match the private key
+	expected, err := parseSSHPublicKey(publicKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !expected.(interface{ Equal(crypto.PublicKey) bool }).Equal(pub) {
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	return key, nil
+}
@@ -0,0 +1,250 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/subtle"
+)
+
+// KeySet is a set of candidate keys for verifying tokens, for example the old
+// and new keys during a key rotation. Passing a *KeySet as the key to Parse
+// verifies the token against each key in turn, starting with those whose ID
+// matches the token's "kid" header, and the signature is valid if any of
+// them verifies it. The parsed token implements VerifiedKeyToken, whose
+// VerifiedKeyID returns the ID of the key that did.
+//
+// Keys added with AddWithAlgorithm are bound to an algorithm and are only
+// tried if the token's "alg" header names it. If every key is bound then the
+// algorithm passed to Parse may be nil.
+//
+// Unless every key is used with one of this package's RSA, ECDSA or EdDSA
+// algorithms, every key is tried even after one matches and the result is
+// chosen in constant time. For the HMAC algorithms, including wrapped or
+// custom ones, the time taken then doesn't reveal which secret signed the
+// token, provided each verification takes the same time whether or not it
+// succeeds. A KeySet mustn't be modified while it's being used to parse
+// tokens.
+type KeySet struct {
+	entries []keySetEntry
+}
+
+type keySetEntry struct {
+	id  string
+	alg SigningAlgorithm
+	key interface{}
+}
+
+// NewKeySet creates an empty KeySet
+func NewKeySet() *KeySet {
+	return &KeySet{}
+}
+
+// Add adds a key with the given ID, which may be empty. The key may be any
+// key accepted by the algorithm the KeySet is used with, such as a
+// PreparedKey. ErrWeakKey is returned, and the key isn't added, if it's an
+// HMAC secret or RSA key that is too short for every algorithm that would
+// accept it.
+func (s *KeySet) Add(id string, key interface{}) error {
+	if err := checkKey(nil, key); err != nil {
+		return err
+	}
+
+	s.entries = append(s.entries, keySetEntry{id: id, key: key})
+
+	return nil
+}
+
+// AddWithAlgorithm adds a key that is only used with alg. If alg implements
+// KeyPreparer the key is checked by preparing it, so a key that alg would
+// refuse, for example because it's too weak, isn't added and the error is
+// returned.
+func (s *KeySet) AddWithAlgorithm(id string, alg SigningAlgorithm, key interface{}) error {
+	if alg == nil {
+		return ErrUnsupportedAlgorithm
+	}
+
+	if err := checkKey(alg, key); err != nil {
+		return err
+	}
+
+	s.entries = append(s.entries, keySetEntry{id: id, alg: alg, key: key})
+
+	return nil
+}
+
+// checkKey checks that the key can be used with alg. If alg is nil, or can't
+// prepare keys, it only checks that the key isn't too weak for every
+// algorithm that accepts it.
+func checkKey(alg SigningAlgorithm, key interface{}) error {
+	if _, ok := key.(*PreparedKey); ok {
+		return nil
+	}
+
+	if preparer, ok := alg.(KeyPreparer); ok {
+		_, err := preparer.PrepareKey(key)
+		return err
+	}
+
+	var rsaKey *rsa.PublicKey
+
+	switch k := key.(type) {
+	case string:
+		return checkSecret([]byte(k))
+	case []byte:
+		return checkSecret(k)
+	case HMACKey:
+		if len(k.Secret) < HS256.minKeySize() {
+			return ErrWeakKey
+		}
+	case *rsa.PublicKey:
+		rsaKey = k
+	case *rsa.PrivateKey:
+		if k != nil {
+			rsaKey = &k.PublicKey
+		}
+	case RSAPublicKey:
+		rsaKey = k.Key
+	case RSAPrivateKey:
+		if k.Key != nil {
+			rsaKey = &k.Key.PublicKey
+		}
+	default:
+		return nil
+	}
+
+	if rsaKey != nil && rsaKey.N.BitLen() < MinRSAKeySize {
+		return ErrWeakKey
+	}
+
+	return nil
+}
+
+// checkSecret checks a key passed as a string or byte array, which may be an
+// HMAC secret or an encoded asymmetric key
+func checkSecret(key []byte) error {
+	if !looksLikeAsymmetricKey(key) {
+		if len(key) < HS256.minKeySize() {
+			return ErrWeakKey
+		}
+
+		return nil
+	}
+
+	if public, err := ParsePublicKey(key); err == nil {
+		if rsaKey, ok := public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < MinRSAKeySize {
+			return ErrWeakKey
+		}
+	}
+
+	return nil
+}
+
+// algorithm returns the algorithm to verify the entry's key with, or nil if
+// the key mustn't be used for a token signed with headerAlg
+func (e *keySetEntry) algorithm(alg SigningAlgorithm, headerAlg string) SigningAlgorithm {
+	if e.alg == nil {
+		return alg
+	}
+
+	if e.alg.Name() != headerAlg || (alg != nil && alg.Name() != headerAlg) {
+		return nil
+	}
+
+	return e.alg
+}
+
+// exhaustive reports whether every key must be tried, which is the case
+// unless every key would be used with one of this package's asymmetric
+// algorithms. Wrapped and custom algorithms may be HMAC so are included.
+func (s *KeySet) exhaustive(alg SigningAlgorithm) bool {
+	for _, entry := range s.entries {
+		effective := entry.alg
+		if effective == nil {
+			effective = alg
+		}
+
+		switch effective.(type) {
+		case *SigningAlgorithmRSA, *SigningAlgorithmECDSA, *SigningAlgorithmEdDSA:
+		default:
+			return true
+		}
+	}
+
+	return false
+}
+
+// verify verifies the signature against the keys in the set and returns the
+// ID of the key that verified it. Keys with the ID kid are tried first. If
+// exhaustive is true every key is tried, the first to match is chosen in
+// constant time and the context is only checked at the end.
+func (s *KeySet) verify(ctx context.Context, alg SigningAlgorithm, headerAlg string, kid string, payload []byte, signature []byte, exhaustive bool) (string, error) {
+	if len(s.entries) == 0 {
+		return "", ErrInvalidKey
+	}
+
+	matched := -1
+
+	// the first pass tries the keys whose ID matches kid and the second pass
+	// tries the rest
+	for pass := 0; pass < 2; pass++ {
+		for i := range s.entries {
+			entry := &s.entries[i]
+
+			if (entry.id == kid && kid != "") != (pass == 0) {
+				continue
+			}
+
+			entryAlg := entry.algorithm(alg, headerAlg)
+
+			if entryAlg == nil {
+				continue
+			}
+
+			if !exhaustive {
+				if err := ctx.Err(); err != nil {
+					return "", err
+				}
+
+				if verifyContext(ctx, entryAlg, payload, signature, entry.key) == nil {
+					return entry.id, nil
+				}
+
+				continue
+			}
+
+			var ok int
+			if verifyContext(ctx, entryAlg, payload, signature, entry.key) == nil {
+				ok = 1
+			}
+
+			// keep the first key that matched
+			first := ok & subtle.ConstantTimeEq(int32(matched), -1)
+			matched = subtle.ConstantTimeSelect(first, i, matched)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	if matched < 0 {
+		return "", ErrBadSignature
+	}
+
+	return s.entries[matched].id, nil
+}
+
+// verifyKey verifies the signature with key, which may be a *KeySet. It
+// returns the ID of the key in the set that verified the signature.
+func verifyKey(ctx context.Context, alg SigningAlgorithm, header map[string]interface{}, payload []byte, signature []byte, key interface{}) (string, error) {
+	set, ok := key.(*KeySet)
+
+	if !ok {
+		return "", verifyContext(ctx, alg, payload, signature, key)
+	}
+
+	headerAlg, _ := header["alg"].(string)
+	kid, _ := header["kid"].(string)
+
+	return set.verify(ctx, alg, headerAlg, kid, payload, signature, set.exhaustive(alg))
+}
@@ -0,0 +1,198 @@
+package jwt
+
+import (
+	"context"
+	"testing"
+)
+
+func TestKeySetRotation(t *testing.T) {
+	oldKey, _ := GenerateKey(ES256)
+	newKey, _ := GenerateKey(ES256)
+	unknown, _ := GenerateKey(ES256)
+
+	set := NewKeySet()
+	set.Add(oldKey.KeyID, oldKey.PublicKey)
+	set.Add(newKey.KeyID, newKey.PublicKey)
+
+	for _, test := range []struct {
+		key *GeneratedKey
+		kid string
+	}{
+		{oldKey, oldKey.KeyID},
+		{newKey, newKey.KeyID},
+		// a missing or wrong kid falls back to trying every key
+		{newKey, ""},
+		{oldKey, newKey.KeyID},
+	} {
+		tok := NewToken(ES256).(HeaderToken)
+		tok.SetHeader("kid", test.kid)
+
+		encoded, _ := tok.Encode(test.key.PrivateKey)
+
+		parsed, err := ParseToken(encoded, ES256, set)
+
+		if err != nil {
+			t.Errorf("[%v] Error while verifying: %v", test.kid, err)
+			continue
+		}
+
+		if id := parsed.(VerifiedKeyToken).VerifiedKeyID(); id != test.key.KeyID {
+			t.Errorf("[%v] Expected the token to be verified by %v but got %v", test.kid, test.key.KeyID, id)
+		}
+	}
+
+	tok := NewToken(ES256).(HeaderToken)
+	tok.SetHeader("kid", oldKey.KeyID)
+
+	encoded, _ := tok.Encode(unknown.PrivateKey)
+
+	parsed, err := ParseToken(encoded, ES256, set)
+
+	if err != BadSignatureError {
+		t.Errorf("Expected BadSignatureError but got %v", err)
+	}
+
+	if id := parsed.(VerifiedKeyToken).VerifiedKeyID(); id != "" {
+		t.Errorf("Expected no verified key ID but got %v", id)
+	}
+
+	if _, err := ParseToken(encoded, ES256, NewKeySet()); err != BadSignatureError {
+		t.Errorf("Expected BadSignatureError for an empty set but got %v", err)
+	}
+}
+
+func TestKeySetHMAC(t *testing.T) {
+	set := NewKeySet()
+
+	if err := set.Add("old", "old-secret-for-hs256-rotation-tests"); err != nil {
+		t.Fatalf("Error while adding a key: %v", err)
+	}
+
+	if err := set.Add("new", testKey); err != nil {
+		t.Fatalf("Error while adding a key: %v", err)
+	}
+
+	if err := set.Add("short", "weak"); err != ErrWeakKey {
+		t.Errorf("Expected ErrWeakKey for a short secret but got %v", err)
+	}
+
+	if err := set.AddWithAlgorithm("short", HS512, testKey); err != ErrWeakKey {
+		t.Errorf("Expected ErrWeakKey for a secret too short for HS512 but got %v", err)
+	}
+
+	parsed, err := ParseToken(testToken, HS256, set)
+
+	if err != nil {
+		t.Fatalf("Error while verifying: %v", err)
+	}
+
+	if id := parsed.(VerifiedKeyToken).VerifiedKeyID(); id != "new" {
+		t.Errorf("Expected the token to be verified by new but got %v", id)
+	}
+}
+
+// recordingAlgorithm records the keys it was asked to verify with
+type recordingAlgorithm struct {
+	SigningAlgorithm
+	keys []interface{}
+}
+
+func (alg *recordingAlgorithm) Verify(payload string, signature string, key interface{}) error {
+	alg.keys = append(alg.keys, key)
+	return alg.SigningAlgorithm.Verify(payload, signature, key)
+}
+
+func TestKeySetOrder(t *testing.T) {
+	set := NewKeySet()
+	set.Add("a", "a-secret-that-is-long-enough-for-hs256")
+	set.Add("b", testKey)
+	set.Add("c", "c-secret-that-is-long-enough-for-hs256")
+
+	payload := []byte("payload")
+	sig, _ := HS256.SignBytes(payload, testKey)
+
+	for _, test := range []struct {
+		kid        string
+		exhaustive bool
+		tried      int
+	}{
+		// the key with the matching kid is tried first
+		{"b", false, 1},
+		{"", false, 2},
+		{"c", false, 3},
+		{"b", true, 3},
+	} {
+		alg := &recordingAlgorithm{SigningAlgorithm: HS256}
+
+		id, err := set.verify(context.Background(), alg, "HS256", test.kid, payload, sig, test.exhaustive)
+
+		if err != nil || id != "b" {
+			t.Errorf("[%v] Expected b to verify but got %v (%v)", test.kid, id, err)
+		}
+
+		if len(alg.keys) != test.tried {
+			t.Errorf("[%v] Expected %v keys to be tried but %v were", test.kid, test.tried, len(alg.keys))
+		}
+	}
+}
+
+func TestKeySetExhaustiveOrder(t *testing.T) {
+	// both keys verify the signature so the order decides which is reported
+	set := NewKeySet()
+	set.Add("a", testKey)
+	set.Add("b", testKey)
+
+	payload := []byte("payload")
+	sig, _ := HS256.SignBytes(payload, testKey)
+
+	for _, test := range []struct {
+		kid string
+		id  string
+	}{
+		{"", "a"},
+		{"a", "a"},
+		{"b", "b"},
+		{"c", "a"},
+	} {
+		id, err := set.verify(context.Background(), HS256, "HS256", test.kid, payload, sig, true)
+
+		if err != nil || id != test.id {
+			t.Errorf("[%v] Expected %v to verify but got %v (%v)", test.kid, test.id, id, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := set.verify(ctx, HS256, "HS256", "", payload, sig, true); err != context.Canceled {
+		t.Errorf("Expected context.Canceled but got %v", err)
+	}
+}
+
+func TestKeySetExhaustive(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		alg        SigningAlgorithm
+		exhaustive bool
+	}{
+		{"HS256", HS256, true},
+		{"wrapped HS256", &recordingAlgorithm{SigningAlgorithm: HS256}, true},
+		{"RS256", RS256, false},
+		{"ES256", ES256, false},
+		{"EdDSA", EdDSA, false},
+		{"wrapped RS256", &recordingAlgorithm{SigningAlgorithm: RS256}, true},
+	} {
+		set := &KeySet{entries: []keySetEntry{{id: "a"}, {id: "b", alg: test.alg}}}
+
+		if exhaustive := set.exhaustive(test.alg); exhaustive != test.exhaustive {
+			t.Errorf("[%v] Expected exhaustive to be %v but got %v", test.name, test.exhaustive, exhaustive)
+		}
+	}
+
+	// a key bound to an HMAC algorithm makes the whole set exhaustive
+	set := &KeySet{entries: []keySetEntry{{id: "a"}, {id: "b", alg: HS256}}}
+
+	if !set.exhaustive(RS256) {
+		t.Errorf("Expected a set with an HMAC key to be exhaustive")
+	}
+}
@@ -0,0 +1,225 @@
+package jwt
+
+import (
+	"crypto"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// KeyStore persists the keys of a KeyManager
+type KeyStore interface {
+	// Load returns the saved keys
+	Load() ([]*ManagedKey, error)
+
+	// Save replaces the saved keys with keys
+	Save(keys []*ManagedKey) error
+}
+
+// DirKeyStore is a KeyStore that saves each key as a JSON file in a
+// directory. The files hold private keys so are only readable by their owner.
+// Only files with the ".key.json" suffix are read or removed, so the
+// directory may hold other files. Key IDs may only contain letters, digits,
+// '-' and '_', which thumbprints generated by GenerateKey do.
+type DirKeyStore struct {
+	Dir string
+}
+
+// NewDirKeyStore creates a DirKeyStore for the directory, which is created
+// when keys are first saved
+func NewDirKeyStore(dir string) *DirKeyStore {
+	return &DirKeyStore{Dir: dir}
+}
+
+const keyFileSuffix = ".key.json"
+
+// storedKey is the format of a key file. The key is a private JWK, or a
+// public JWK for verification-only keys.
+type storedKey struct {
+	ID        string    `json:"kid"`
+	Algorithm string    `json:"alg"`
+	Key       *JWK      `json:"key"`
+	SignFrom  time.Time `json:"sign_from"`
+	RetireAt  time.Time `json:"retire_at"`
+}
+
+// validKeyID reports whether id can be used as a file name
+func validKeyID(id string) bool {
+	if id == "" {
+		return false
+	}
+
+	for _, c := range id {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Load reads the keys from the directory. A directory that doesn't exist
+// holds no keys.
+func (s *DirKeyStore) Load() ([]*ManagedKey, error) {
+	names, err := filepath.Glob(filepath.Join(s.Dir, "*"+keyFileSuffix))
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*ManagedKey, 0, len(names))
+
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var stored storedKey
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return nil, err
+		}
+
+		key, err := stored.managedKey()
+
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// managedKey converts the stored key back to a ManagedKey
+func (k *storedKey) managedKey() (*ManagedKey, error) {
+	alg, ok := algorithms[k.Algorithm]
+
+	if !ok {
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	if k.Key == nil {
+		return nil, ErrMissingKeyData
+	}
+
+	key, err := k.Key.Key()
+
+	if err != nil {
+		return nil, err
+	}
+
+	managed := &ManagedKey{
+		ID:        k.ID,
+		Algorithm: alg,
+		SignFrom:  k.SignFrom,
+		RetireAt:  k.RetireAt,
+	}
+
+	if k.Key.IsPrivate() {
+		managed.PrivateKey = key
+	} else {
+		managed.PublicKey = key
+	}
+
+	if signer, ok := key.(crypto.Signer); ok {
+		managed.PublicKey = signer.Public()
+	}
+
+	return managed, nil
+}
+
+// Save writes a file for each key and removes the files of keys that aren't
+// in keys. Each file is written to a temporary file first and renamed so that
+// a failed save doesn't leave a partially written key.
+func (s *DirKeyStore) Save(keys []*ManagedKey) error {
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+
+	saved := make(map[string]bool, len(keys))
+
+	for _, key := range keys {
+		if !validKeyID(key.ID) {
+			return ErrInvalidKeyID
+		}
+
+		material := key.PrivateKey
+		if material == nil {
+			material = key.PublicKey
+		}
+
+		jwk, err := NewJWK(material)
+
+		if err != nil {
+			return err
+		}
+
+		data, err := json.MarshalIndent(&storedKey{
+			ID:        key.ID,
+			Algorithm: key.Algorithm.Name(),
+			Key:       jwk,
+			SignFrom:  key.SignFrom,
+			RetireAt:  key.RetireAt,
+		}, "", "  ")
+
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Join(s.Dir, key.ID+keyFileSuffix)
+
+		if err := writeFileAtomic(name, data); err != nil {
+			return err
+		}
+
+		saved[name] = true
+	}
+
+	names, err := filepath.Glob(filepath.Join(s.Dir, "*"+keyFileSuffix))
+
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if !saved[name] {
+			if err := os.Remove(name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and
+// renames it over name
+func writeFileAtomic(name string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+strings.TrimSuffix(filepath.Base(name), keyFileSuffix)+"-*.tmp")
+
+	if err != nil {
+		return err
+	}
+
+	// CreateTemp creates the file with mode 0600
+	_, err = f.Write(data)
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+	}
+
+	return err
+}
@@ -0,0 +1,114 @@
+package jwt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDirKeyStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keys")
+	store := NewDirKeyStore(dir)
+
+	m, err := NewKeyManager(store)
+
+	if err != nil {
+		t.Fatalf("Error loading from a missing directory: %v", err)
+	}
+
+	signFrom := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	retireAt := signFrom.Add(90 * 24 * time.Hour)
+
+	for _, alg := range []SigningAlgorithm{HS256, RS256, ES256, EdDSA} {
+		if _, err := m.Rotate(alg, signFrom, retireAt); err != nil {
+			t.Fatalf("[%v] Error while rotating: %v", alg.Name(), err)
+		}
+	}
+
+	partner, _ := GenerateKey(ES384)
+	m.Add(&ManagedKey{ID: "partner", Algorithm: ES384, PublicKey: partner.PublicKey})
+
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("not a key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := NewKeyManager(store)
+
+	if err != nil {
+		t.Fatalf("Error while loading: %v", err)
+	}
+
+	keys := loaded.Keys()
+
+	if len(keys) != 5 {
+		t.Fatalf("Expected 5 keys but got %v", len(keys))
+	}
+
+	for _, key := range keys {
+		var original *ManagedKey
+		for _, k := range m.Keys() {
+			if k.ID == key.ID {
+				original = k
+			}
+		}
+
+		if original == nil {
+			t.Errorf("[%v] Unexpected key", key.ID)
+			continue
+		}
+
+		if key.Algorithm != original.Algorithm || !key.SignFrom.Equal(original.SignFrom) || !key.RetireAt.Equal(original.RetireAt) {
+			t.Errorf("[%v] The key's algorithm or schedule doesn't match", key.ID)
+		}
+
+		if (key.PrivateKey == nil) != (original.PrivateKey == nil) || (key.PublicKey == nil) != (original.PublicKey == nil) {
+			t.Errorf("[%v] Expected the same keys to be present", key.ID)
+		}
+
+		info, err := os.Stat(filepath.Join(dir, key.ID+keyFileSuffix))
+
+		if err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("[%v] Expected the key file to be private (%v)", key.ID, err)
+		}
+	}
+
+	// the loaded keys sign tokens the original manager accepts
+	tok, _ := loaded.NewToken()
+	encoded, err := loaded.Encode(tok)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ParseToken(encoded, nil, m.KeySet()); err != nil {
+		t.Errorf("Error while verifying: %v", err)
+	}
+
+	// removing a key removes its file but leaves other files alone
+	if err := loaded.Remove("partner"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "partner"+keyFileSuffix)); !os.IsNotExist(err) {
+		t.Errorf("Expected the key file to be removed but got %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "README")); err != nil {
+		t.Errorf("Expected other files to be left alone but got %v", err)
+	}
+}
+
+func TestDirKeyStoreInvalidID(t *testing.T) {
+	m, _ := NewKeyManager(NewDirKeyStore(t.TempDir()))
+
+	for _, id := range []string{"../escape", "a/b", ".hidden"} {
+		if err := m.Add(&ManagedKey{ID: id, Algorithm: HS256, PrivateKey: []byte(testKey)}); err != ErrInvalidKeyID {
+			t.Errorf("[%v] Expected ErrInvalidKeyID but got %v", id, err)
+		}
+	}
+
+	if keys := m.Keys(); len(keys) != 0 {
+		t.Errorf("Expected keys that failed to save not to be added but got %v", len(keys))
+	}
+}
@@ -0,0 +1,113 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"errors"
+)
+
+// ErrAlgorithmMismatch is returned when a key is used with an algorithm it
+// isn't bound to
+var ErrAlgorithmMismatch = errors.New("The key is not allowed for use with this algorithm")
+
+// The key types below bind a key to the algorithms it may be used with. Since
+// keys are passed as interface{}, a string could otherwise be used as both an
+// HMAC secret and an encoded RSA or Ed25519 key, so an attacker who can choose
+// the algorithm could have a public key used as an HMAC secret. Each type is
+// only accepted by its own family of algorithms and, if Algorithms is
+// non-empty, only by the algorithms named in it.
+
+// HMACKey is a secret for use with the HMAC algorithms
+type HMACKey struct {
+	Secret     []byte
+	Algorithms []string
+}
+
+// RSAPrivateKey is a private key for use with the RSA algorithms. It can also
+// be used to verify signatures.
+type RSAPrivateKey struct {
+	Key        *rsa.PrivateKey
+	Algorithms []string
+}
+
+// RSAPublicKey is a public key for use with the RSA algorithms
+type RSAPublicKey struct {
+	Key        *rsa.PublicKey
+	Algorithms []string
+}
+
+// allowsAlgorithm reports whether the algorithm is in the list of algorithms
+// bound to a key. An empty list allows any algorithm of the key's family.
+func allowsAlgorithm(algorithms []string, alg SigningAlgorithm) bool {
+	if len(algorithms) == 0 {
+		return true
+	}
+
+	for _, name := range algorithms {
+		if name == alg.Name() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// invalidKey returns the error for a key that an algorithm doesn't accept,
+// which is ErrAlgorithmMismatch if the key is bound to another family of
+// algorithms
+func invalidKey(key interface{}) error {
+	switch key.(type) {
+	case HMACKey, RSAPrivateKey, RSAPublicKey, ECPrivateKey, ECPublicKey, Ed25519PrivateKey, Ed25519PublicKey:
+		return ErrAlgorithmMismatch
+	}
+
+	return ErrInvalidKey
+}
+
+// looksLikeAsymmetricKey reports whether a key passed as a string or byte
+// array holds a PEM block, or an asymmetric key in any other format accepted
+// by ParsePublicKey, rather than a secret
+func looksLikeAsymmetricKey(key []byte) bool {
+	if bytes.Contains(key, []byte("-----BEGIN ")) {
+		return true
+	}
+
+	// this runs whenever a raw secret is used, so only keys with the right
+	// structure are parsed
+	trimmed := bytes.TrimLeft(key, " \t\r\n")
+	if !isDERSequence(key) && !bytes.HasPrefix(trimmed, []byte("ssh-")) && !bytes.HasPrefix(trimmed, []byte("ecdsa-")) {
+		return false
+	}
+
+	_, err := ParsePublicKey(key)
+
+	return err == nil
+}
+
+// isDERSequence reports whether key is exactly one DER encoded SEQUENCE, as
+// every DER encoded key is
+func isDERSequence(key []byte) bool {
+	if len(key) < 2 || key[0] != 0x30 {
+		return false
+	}
+
+	length, header := int(key[1]), 2
+
+	// the long form gives the number of length bytes that follow
+	if length >= 0x80 {
+		n := length & 0x7f
+
+		if n == 0 || n > 3 || len(key) < header+n {
+			return false
+		}
+
+		length = 0
+		for _, b := range key[header : header+n] {
+			length = length<<8 | int(b)
+		}
+
+		header += n
+	}
+
+	return header+length == len(key)
+}
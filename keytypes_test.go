@@ -0,0 +1,148 @@
+package jwt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+)
+
+func TestHMACKey(t *testing.T) {
+	segments := strings.Split(hs256Test, ".")
+	payload := strings.Join(segments[0:2], ".")
+
+	sig, err := HS256.Sign(payload, HMACKey{Secret: hmacTestKey})
+
+	if err != nil {
+		t.Errorf("Error while signing token: %v", err)
+	}
+
+	if sig != segments[2] {
+		t.Errorf("Incorrect signature.\nwas:\n%v\nexpecting:\n%v", sig, segments[2])
+	}
+
+	bound := HMACKey{Secret: hmacTestKey, Algorithms: []string{"HS256"}}
+
+	if err := HS256.Verify(payload, segments[2], bound); err != nil {
+		t.Errorf("Error while verifying signature: %v", err)
+	}
+
+	if _, err := HS512.Sign(payload, bound); err != ErrAlgorithmMismatch {
+		t.Errorf("Expected ErrAlgorithmMismatch signing with HS512 but got %v", err)
+	}
+
+	if _, err := HS512.PrepareKey(bound); err != ErrAlgorithmMismatch {
+		t.Errorf("Expected ErrAlgorithmMismatch preparing for HS512 but got %v", err)
+	}
+
+	if _, err := RS256.Sign(payload, bound); err != ErrAlgorithmMismatch {
+		t.Errorf("Expected ErrAlgorithmMismatch signing with RS256 but got %v", err)
+	}
+}
+
+func TestRSAKeyTypes(t *testing.T) {
+	private, _ := ParseRSAPrivateKeyFromPEM([]byte(rsaPrivateKey))
+	public, _ := ParseRSAPublicKeyFromPEM([]byte(rsaPublicKey))
+
+	segments := strings.Split(rs256Test, ".")
+	payload := strings.Join(segments[0:2], ".")
+
+	sig, err := RS256.Sign(payload, RSAPrivateKey{Key: private, Algorithms: []string{"RS256"}})
+
+	if err != nil {
+		t.Errorf("Error while signing token: %v", err)
+	}
+
+	if sig != segments[2] {
+		t.Errorf("Incorrect signature.\nwas:\n%v\nexpecting:\n%v", sig, segments[2])
+	}
+
+	for _, key := range []interface{}{RSAPublicKey{Key: public}, RSAPrivateKey{Key: private}} {
+		if err := RS256.Verify(payload, segments[2], key); err != nil {
+			t.Errorf("Error while verifying signature with %T: %v", key, err)
+		}
+	}
+
+	bound := RSAPublicKey{Key: public, Algorithms: []string{"RS512"}}
+
+	if err := RS256.Verify(payload, segments[2], bound); err != ErrAlgorithmMismatch {
+		t.Errorf("Expected ErrAlgorithmMismatch verifying with RS256 but got %v", err)
+	}
+
+	if err := HS256.Verify(payload, segments[2], RSAPublicKey{Key: public}); err != ErrAlgorithmMismatch {
+		t.Errorf("Expected ErrAlgorithmMismatch verifying with HS256 but got %v", err)
+	}
+}
+
+func TestAlgorithmConfusion(t *testing.T) {
+	// an attacker who knows the RSA public key signs a token using it as an
+	// HMAC secret, hoping the verifier picks HS256 from the header
+	forged := NewToken(HS256)
+	forged.SetClaim("admin", true)
+
+	payload := "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJhZG1pbiI6dHJ1ZX0"
+	sig := encode(rawHMAC(payload, rsaPublicKey))
+
+	for _, key := range []interface{}{rsaPublicKey, []byte(rsaPublicKey)} {
+		if err := HS256.Verify(payload, sig, key); err != ErrAlgorithmMismatch {
+			t.Errorf("Expected ErrAlgorithmMismatch using a PEM %T as a secret but got %v", key, err)
+		}
+
+		if _, err := forged.Encode(key); err != ErrAlgorithmMismatch {
+			t.Errorf("Expected ErrAlgorithmMismatch signing with a PEM %T but got %v", key, err)
+		}
+	}
+
+	if _, err := ParseToken(payload+"."+sig, HS256, rsaPublicKey); err != BadSignatureError {
+		t.Errorf("Expected BadSignatureError but got %v", err)
+	}
+
+	// the same applies to the other formats accepted by ParsePublicKey
+	block, _ := pem.Decode([]byte(rsaPublicKey))
+	edPublic, _ := ParsePublicKey([]byte(sshEd25519PublicKey))
+	edDER, _ := x509.MarshalPKIXPublicKey(edPublic)
+
+	for _, key := range []interface{}{block.Bytes, string(block.Bytes), edDER, sshEd25519PublicKey, []byte(sshEd25519PublicKey)} {
+		if _, err := HS256.Sign(payload, key); err != ErrAlgorithmMismatch {
+			t.Errorf("Expected ErrAlgorithmMismatch using an encoded public key %T as a secret but got %v", key, err)
+		}
+	}
+
+	// secrets that merely look like DER or contain spaces are fine, and an
+	// HMACKey is trusted to hold a secret
+	for _, key := range []interface{}{
+		"0 secret that is long enough for hs256",
+		[]byte{0x30, 0x82, 0x01, 0x22, 0x30, 0x0d, 0x06, 0x09, 0x2a, 0x86, 0x48, 0x86, 0xf7, 0x0d, 0x01, 0x01, 0x01, 0x05, 0x00, 0x03, 0x82, 0x01, 0x0f, 0x00, 0x30, 0x82, 0x01, 0x0a, 0x02, 0x82, 0x01, 0x01, 0x00},
+		HMACKey{Secret: block.Bytes},
+	} {
+		if _, err := HS256.Sign(payload, key); err != nil {
+			t.Errorf("Unexpected error signing with %T: %v", key, err)
+		}
+	}
+}
+
+func TestLooksLikeAsymmetricKeyAllocs(t *testing.T) {
+	// the check runs on every use of a raw secret so secrets mustn't be
+	// parsed as keys
+	for _, secret := range [][]byte{
+		[]byte("0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f9"),
+		[]byte("correct horse battery staple and then some more words"),
+	} {
+		if looksLikeAsymmetricKey(secret) {
+			t.Errorf("Expected %q not to look like a key", secret)
+		}
+
+		if allocs := testing.AllocsPerRun(100, func() { looksLikeAsymmetricKey(secret) }); allocs != 0 {
+			t.Errorf("Expected no allocations checking %q but got %v", secret, allocs)
+		}
+	}
+}
+
+// rawHMAC computes an HMAC-SHA256 without the checks made by HS256
+func rawHMAC(payload string, secret string) []byte {
+	hasher := hmac.New(sha256.New, []byte(secret))
+	hasher.Write([]byte(payload))
+	return hasher.Sum(nil)
+}
@@ -0,0 +1,150 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+)
+
+// Errors relating to verifying a JWS against a policy
+var (
+	ErrPolicyNotSatisfied = errors.New("The signatures don't satisfy the verification policy")
+	ErrNoMatchingKey      = errors.New("No key matches the signature's algorithm and key ID")
+)
+
+// SigningKey is a key to sign a JWS with. If KeyID is set it's added to the
+// protected header as "kid".
+type SigningKey struct {
+	Algorithm SigningAlgorithm
+	Key       interface{}
+	KeyID     string
+}
+
+// VerificationKey is a key to verify the signatures of a JWS with. It's only
+// tried on signatures whose "alg" header names Algorithm and, if both have a
+// key ID, whose "kid" header matches KeyID.
+type VerificationKey struct {
+	Algorithm SigningAlgorithm
+	Key       interface{}
+	KeyID     string
+}
+
+// SignatureResult is the outcome of verifying one signature of a JWS. Key is
+// the key that verified it, or nil with Err saying why none did.
+type SignatureResult struct {
+	Signature *Signature
+	Key       *VerificationKey
+	Err       error
+}
+
+// VerificationPolicy decides whether a JWS is valid given how many of the
+// total verification keys verified at least one of its signatures
+type VerificationPolicy func(verified int, total int) bool
+
+// RequireAny is satisfied if any of the keys verified a signature
+func RequireAny() VerificationPolicy {
+	return func(verified int, total int) bool {
+		return verified > 0
+	}
+}
+
+// RequireAll is satisfied if every key verified a signature
+func RequireAll() VerificationPolicy {
+	return func(verified int, total int) bool {
+		return verified > 0 && verified == total
+	}
+}
+
+// RequireAtLeast is satisfied if at least n of the keys verified a signature
+func RequireAtLeast(n int) VerificationPolicy {
+	return func(verified int, total int) bool {
+		return verified > 0 && verified >= n
+	}
+}
+
+// SignAll signs the payload with each of the keys, adding a signature for
+// each. If any key fails no signatures are added.
+func (j *JWS) SignAll(keys ...SigningKey) error {
+	return j.SignAllContext(context.Background(), keys...)
+}
+
+// SignAllContext is like SignAll but passes ctx through to the signing
+// algorithms
+func (j *JWS) SignAllContext(ctx context.Context, keys ...SigningKey) error {
+	signed := &JWS{payload: j.payload}
+
+	for _, key := range keys {
+		var protected map[string]interface{}
+		if key.KeyID != "" {
+			protected = map[string]interface{}{"kid": key.KeyID}
+		}
+
+		if _, err := signed.SignContext(ctx, key.Algorithm, key.Key, protected, nil); err != nil {
+			return err
+		}
+	}
+
+	j.Signatures = append(j.Signatures, signed.Signatures...)
+
+	return nil
+}
+
+// VerifyPolicy verifies each signature against the keys and checks the
+// result with the policy. A key counts once however many signatures it
+// verifies. The result for each signature is returned in order even if the
+// policy isn't satisfied, in which case the error is ErrPolicyNotSatisfied.
+func (j *JWS) VerifyPolicy(policy VerificationPolicy, keys ...VerificationKey) ([]*SignatureResult, error) {
+	return j.VerifyPolicyContext(context.Background(), policy, keys...)
+}
+
+// VerifyPolicyContext is like VerifyPolicy but passes ctx through to the
+// signing algorithms
+func (j *JWS) VerifyPolicyContext(ctx context.Context, policy VerificationPolicy, keys ...VerificationKey) ([]*SignatureResult, error) {
+	if len(j.Signatures) == 0 {
+		return nil, ErrNoSignatures
+	}
+
+	var (
+		results  = make([]*SignatureResult, len(j.Signatures))
+		verified = make([]bool, len(keys))
+	)
+
+	for i, s := range j.Signatures {
+		result := &SignatureResult{Signature: s, Err: ErrNoMatchingKey}
+		results[i] = result
+
+		for k := range keys {
+			key := &keys[k]
+
+			if key.Algorithm == nil || key.Algorithm.Name() != s.Algorithm() {
+				continue
+			}
+
+			if kid := s.KeyID(); kid != "" && key.KeyID != "" && kid != key.KeyID {
+				continue
+			}
+
+			if _, result.Err = s.verify(ctx, key.Algorithm, key.Key); result.Err == nil {
+				result.Key = key
+				verified[k] = true
+				break
+			}
+
+			if err := ctx.Err(); err != nil {
+				return results, err
+			}
+		}
+	}
+
+	count := 0
+	for _, ok := range verified {
+		if ok {
+			count++
+		}
+	}
+
+	if !policy(count, len(keys)) {
+		return results, ErrPolicyNotSatisfied
+	}
+
+	return results, nil
+}
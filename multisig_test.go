@@ -0,0 +1,105 @@
+package jwt
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJWSVerifyPolicy(t *testing.T) {
+	rsaKey, _ := GenerateKey(RS256)
+	ecKey, _ := GenerateKey(ES256)
+	other, _ := GenerateKey(ES256)
+
+	j := NewJWS([]byte("document"))
+
+	err := j.SignAll(
+		SigningKey{Algorithm: RS256, Key: rsaKey.PrivateKey, KeyID: rsaKey.KeyID},
+		SigningKey{Algorithm: ES256, Key: ecKey.PrivateKey, KeyID: ecKey.KeyID},
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := json.Marshal(j)
+	parsed, err := ParseJWS(data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaVerify := VerificationKey{Algorithm: RS256, Key: rsaKey.PublicKey, KeyID: rsaKey.KeyID}
+	ecVerify := VerificationKey{Algorithm: ES256, Key: ecKey.PublicKey, KeyID: ecKey.KeyID}
+	otherVerify := VerificationKey{Algorithm: ES256, Key: other.PublicKey}
+
+	tests := []struct {
+		name   string
+		policy VerificationPolicy
+		keys   []VerificationKey
+		err    error
+	}{
+		{"any", RequireAny(), []VerificationKey{rsaVerify}, nil},
+		{"any of unknown", RequireAny(), []VerificationKey{otherVerify}, ErrPolicyNotSatisfied},
+		{"all", RequireAll(), []VerificationKey{rsaVerify, ecVerify}, nil},
+		{"all with unknown", RequireAll(), []VerificationKey{rsaVerify, ecVerify, otherVerify}, ErrPolicyNotSatisfied},
+		{"at least 2", RequireAtLeast(2), []VerificationKey{otherVerify, ecVerify, rsaVerify}, nil},
+		{"at least 2 of 1", RequireAtLeast(2), []VerificationKey{ecVerify, otherVerify}, ErrPolicyNotSatisfied},
+		{"all of none", RequireAll(), nil, ErrPolicyNotSatisfied},
+	}
+
+	for _, test := range tests {
+		results, err := parsed.VerifyPolicy(test.policy, test.keys...)
+
+		if err != test.err {
+			t.Errorf("[%v] Expected %v but got %v", test.name, test.err, err)
+		}
+
+		if len(results) != 2 {
+			t.Errorf("[%v] Expected a result for each signature but got %v", test.name, len(results))
+		}
+	}
+
+	results, _ := parsed.VerifyPolicy(RequireAny(), otherVerify, ecVerify)
+
+	if results[0].Err != ErrNoMatchingKey || results[0].Key != nil {
+		t.Errorf("Expected no key to match the RS256 signature but got %v", results[0].Err)
+	}
+
+	if results[1].Err != nil || results[1].Key == nil || results[1].Key.KeyID != ecKey.KeyID {
+		t.Errorf("Expected the EC key to verify the ES256 signature but got %v", results[1].Err)
+	}
+
+	// a key with a different ID isn't tried
+	results, _ = parsed.VerifyPolicy(RequireAny(), VerificationKey{Algorithm: ES256, Key: other.PublicKey, KeyID: "wrong"})
+
+	if results[1].Err != ErrNoMatchingKey {
+		t.Errorf("Expected a key with another ID not to be tried but got %v", results[1].Err)
+	}
+
+	results, _ = parsed.VerifyPolicy(RequireAny(), otherVerify)
+
+	if results[1].Err != ErrBadSignature {
+		t.Errorf("Expected ErrBadSignature but got %v", results[1].Err)
+	}
+}
+
+func TestJWSSignAllFailure(t *testing.T) {
+	j := NewJWS([]byte("document"))
+
+	err := j.SignAll(
+		SigningKey{Algorithm: HS256, Key: testKey},
+		SigningKey{Algorithm: ES256, Key: testKey},
+	)
+
+	if err == nil {
+		t.Fatalf("Expected signing with an invalid key to fail")
+	}
+
+	if len(j.Signatures) != 0 {
+		t.Errorf("Expected no signatures to be added but got %v", len(j.Signatures))
+	}
+
+	if _, err := j.VerifyPolicy(RequireAny()); err != ErrNoSignatures {
+		t.Errorf("Expected ErrNoSignatures but got %v", err)
+	}
+}
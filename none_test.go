@@ -0,0 +1,45 @@
+package jwt
+
+import (
+	"testing"
+)
+
+func TestNone(t *testing.T) {
+	tok := NewToken(None)
+	tok.SetClaim("test", "test")
+
+	encoded, err := tok.Encode(UnsafeAllowNone)
+
+	if err != nil {
+		t.Fatalf("An error occured encoding the token: %v", err)
+	}
+
+	if expected := "eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0.eyJ0ZXN0IjoidGVzdCJ9."; encoded != expected {
+		t.Errorf("Token encoding error, expecting:\n%v\n\ngot:\n%v", expected, encoded)
+	}
+
+	if _, err := ParseToken(encoded, None, UnsafeAllowNone); err != nil {
+		t.Errorf("Error occured parsing the token: %v", err)
+	}
+
+	// ordinary keys are rejected, including the sentinel's underlying string
+	for _, key := range []interface{}{nil, "", testKey, []byte(testKey), string(UnsafeAllowNone)} {
+		if _, err := tok.Encode(key); err != ErrInvalidKey {
+			t.Errorf("Expected ErrInvalidKey encoding with key %#v but got %v", key, err)
+		}
+
+		if _, err := ParseToken(encoded, None, key); err != BadSignatureError {
+			t.Errorf("Expected BadSignatureError parsing with key %#v but got %v", key, err)
+		}
+	}
+
+	// a signed token isn't an unsecured one
+	if _, err := ParseToken(testToken, None, UnsafeAllowNone); err != BadSignatureError {
+		t.Errorf("Expected BadSignatureError parsing a signed token but got %v", err)
+	}
+
+	// and an unsecured token doesn't pass as a signed one
+	if _, err := ParseToken(encoded, HMAC, testKey); err != BadSignatureError {
+		t.Errorf("Expected BadSignatureError parsing with HMAC but got %v", err)
+	}
+}
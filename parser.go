@@ -0,0 +1,461 @@
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Parser parses and validates tokens. The zero value parses tokens in the same
+// way as ParseToken, use NewParser to create a Parser with options.
+//
+// A token that fails validation returns its ValidationError flags, or from a
+// Parser created by NewParser a *ParseError wrapping the flags when there are
+// details to report. The zero value only ever returns the flags, so callers
+// of ParseToken may rely on err.(ValidationError).
+type Parser struct {
+	detailedErrors bool
+	strictDecoding bool
+	strictJSON     bool
+	useNumber      bool
+	requiredClaims []string
+	maxLifetime    time.Duration
+	maxAge         time.Duration
+	validators     []ClaimsValidator
+	expectedType   string
+	critHandlers   map[string]CritHandler
+}
+
+// ClaimsValidator checks the claims of a token, returning an error describing
+// why they are unacceptable
+type ClaimsValidator func(claims map[string]interface{}) error
+
+// CritHandler processes a header parameter listed in the "crit" header. It's
+// called with the value of the parameter once the token's signature has been
+// verified and returns an error if the token must be rejected.
+type CritHandler func(value interface{}, t Token) error
+
+// ParserOption configures a Parser
+type ParserOption func(*Parser)
+
+// defaultParser is used by ParseToken and ParseContext
+var defaultParser = &Parser{}
+
+// NewParser creates a new Parser with the given options applied. Unlike the
+// zero value, numbers are decoded as json.Number by default and errors may be
+// returned as a *ParseError.
+func NewParser(options ...ParserOption) *Parser {
+	p := &Parser{
+		detailedErrors: true,
+		useNumber:      true,
+	}
+
+	for _, option := range options {
+		option(p)
+	}
+
+	return p
+}
+
+// WithStrictDecoding makes the Parser reject segments that aren't in the
+// canonical unpadded base64url form required by RFC 7515, so that each token
+// has exactly one valid encoding. Padding, whitespace and line breaks are
+// rejected, as are non-zero trailing bits.
+func WithStrictDecoding() ParserOption {
+	return func(p *Parser) {
+		p.strictDecoding = true
+	}
+}
+
+// WithStrictJSON makes the Parser reject a header or claims set that isn't a
+// single JSON object, contains invalid UTF-8 or has duplicate member names at
+// any depth. RFC 7515 recommends rejecting duplicate names since different
+// parsers may pick different values for them.
+func WithStrictJSON() ParserOption {
+	return func(p *Parser) {
+		p.strictJSON = true
+	}
+}
+
+// WithJSONNumber sets whether numbers in the header and claims are decoded as
+// json.Number rather than float64. float64 can't represent every 64 bit
+// integer so, for example, large user IDs lose precision. The exp and nbf
+// checks and the typed claim accessors such as ClaimInt64 handle both.
+func WithJSONNumber(enabled bool) ParserOption {
+	return func(p *Parser) {
+		p.useNumber = enabled
+	}
+}
+
+// WithRequiredClaims makes the Parser fail with MissingClaimError if any of
+// the named claims are absent or null. Without this a token that has no
+// "exp" claim never expires. Names may be dotted paths as accepted by the
+// typed claim accessors.
+func WithRequiredClaims(claims ...string) ParserOption {
+	return func(p *Parser) {
+		p.requiredClaims = append(p.requiredClaims, claims...)
+	}
+}
+
+// WithMaxLifetime makes the Parser fail with LifetimeExceededError if the
+// time between "iat" and "exp" is longer than d, so that tokens minted with
+// an overly long lifetime are rejected even though they haven't expired. A
+// token without "exp" never expires so always fails, if it has no "iat", or
+// an "iat" in the future, the time remaining until "exp" is used instead.
+func WithMaxLifetime(d time.Duration) ParserOption {
+	return func(p *Parser) {
+		p.maxLifetime = d
+	}
+}
+
+// WithMaxAge makes the Parser fail with TooOldError if more than d has passed
+// since the token was issued according to its "iat" claim. Tokens without an
+// "iat" claim, or with one in the future, always fail.
+func WithMaxAge(d time.Duration) ParserOption {
+	return func(p *Parser) {
+		p.maxAge = d
+	}
+}
+
+// WithValidators adds validators that the Parser runs after the signature and
+// time checks. Every validator runs, and each error returned sets
+// InvalidClaimsError and is included in the ParseError. Validators are only
+// run on tokens with a valid signature.
+func WithValidators(validators ...ClaimsValidator) ParserOption {
+	return func(p *Parser) {
+		p.validators = append(p.validators, validators...)
+	}
+}
+
+// WithExpectedType makes the Parser fail with InvalidTypeError unless the
+// "typ" header matches typ, so that a token minted for one purpose, such as
+// an "at+jwt" access token, can't be used as another (RFC 8725 section 3.11).
+// The comparison ignores case and an "application/" prefix on either side. A
+// token without a "typ" header doesn't match.
+func WithExpectedType(typ string) ParserOption {
+	return func(p *Parser) {
+		p.expectedType = typ
+	}
+}
+
+// WithCritHandler registers the handler for the named header parameter so
+// that tokens listing it in their "crit" header can be accepted. Tokens
+// listing a parameter without a handler fail with CriticalHeaderError, as
+// required by RFC 7515 section 4.1.11.
+func WithCritHandler(name string, handler CritHandler) ParserOption {
+	return func(p *Parser) {
+		if p.critHandlers == nil {
+			p.critHandlers = make(map[string]CritHandler)
+		}
+
+		p.critHandlers[name] = handler
+	}
+}
+
+// Parse parses the token string and validates it using the given
+// SigningAlgorithm and key. The key may be a *KeySet to try several keys, in
+// which case alg may be nil if every key in the set is bound to an algorithm.
+func (p *Parser) Parse(tokenString string, alg SigningAlgorithm, key interface{}) (Token, error) {
+	return p.ParseContext(context.Background(), tokenString, alg, key)
+}
+
+// ParseContext is like Parse but passes ctx through to the signing algorithm
+// if it implements ContextSigningAlgorithm. If ctx is done before the
+// signature has been verified then the context's error is returned.
+func (p *Parser) ParseContext(ctx context.Context, tokenString string, alg SigningAlgorithm, key interface{}) (Token, error) {
+	// locate the dots separating the header, claims and signature
+	headerEnd := strings.IndexByte(tokenString, '.')
+	if headerEnd < 0 {
+		return nil, ErrTokenMalformed
+	}
+
+	claimsEnd := strings.IndexByte(tokenString[headerEnd+1:], '.') + headerEnd + 1
+	if claimsEnd == headerEnd || strings.IndexByte(tokenString[claimsEnd+1:], '.') >= 0 {
+		return nil, ErrTokenMalformed
+	}
+
+	t := &token{
+		raw: tokenString,
+	}
+
+	// the raw token is copied into a pooled buffer, the signing input is a
+	// prefix of it and the rest of the buffer is scratch space for decoding
+	// the segments one at a time
+	n := len(tokenString)
+	bp := getBuffer(n + base64.RawURLEncoding.DecodedLen(n))
+	defer putBuffer(bp)
+
+	buf := append((*bp)[:0], tokenString...)
+	raw, scratch := buf[:n], buf[n:cap(buf)]
+
+	var (
+		segment []byte
+		err     error
+	)
+
+	if segment, err = p.decodeSegment(scratch, raw[:headerEnd]); err != nil {
+		return t, ErrTokenMalformed
+	}
+
+	if err = p.unmarshal(segment, &t.header); err != nil {
+		return t, ErrTokenMalformed
+	}
+
+	if segment, err = p.decodeSegment(scratch, raw[headerEnd+1:claimsEnd]); err != nil {
+		return t, ErrTokenMalformed
+	}
+
+	if err = p.unmarshal(segment, &t.claims); err != nil {
+		return t, ErrTokenMalformed
+	}
+
+	var (
+		errs    ValidationError
+		details []error
+	)
+
+	// check sig
+	if segment, err = p.decodeSegment(scratch, raw[claimsEnd+1:]); err != nil {
+		return t, ErrTokenMalformed
+	}
+
+	if t.keyID, err = verifyKey(ctx, alg, t.header, raw[:claimsEnd], segment, key); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return t, ctxErr
+		}
+
+		errs |= BadSignatureError
+	}
+
+	// check exp, nbf and iat. A claim that is present but isn't a valid
+	// NumericDate fails, otherwise a token could avoid expiring by setting
+	// "exp" to a string.
+	current := TimeFunc()
+	now := current.Unix()
+
+	exp, hasExp, err := timeClaim(t.claims, "exp")
+	if err != nil {
+		errs |= ExpiredError
+		details = append(details, err)
+	} else if hasExp && now > exp.Unix() {
+		errs |= ExpiredError
+	}
+
+	nbf, hasNbf, err := timeClaim(t.claims, "nbf")
+	if err != nil {
+		errs |= NotYetValidError
+		details = append(details, err)
+	} else if hasNbf && now < nbf.Unix() {
+		errs |= NotYetValidError
+	}
+
+	iat, hasIat, err := timeClaim(t.claims, "iat")
+	if err != nil {
+		errs |= InvalidClaimsError
+		details = append(details, err)
+	}
+
+	// the details come from an unauthenticated header unless the signature
+	// verified, so aren't reported
+	verified := errs&BadSignatureError == 0
+
+	if critErrs := p.checkCrit(t, verified); len(critErrs) > 0 {
+		errs |= CriticalHeaderError
+
+		if verified {
+			details = append(details, critErrs...)
+		}
+	}
+
+	errs |= p.checkLifetime(current, exp, hasExp, iat, hasIat)
+
+	if p.expectedType != "" {
+		if typ, _ := t.header["typ"].(string); !typeEqual(typ, p.expectedType) {
+			errs |= InvalidTypeError
+		}
+	}
+
+	for _, claim := range p.requiredClaims {
+		if v, _ := lookupClaim(t.claims, claim); v == nil {
+			errs |= MissingClaimError
+			details = append(details, &ClaimError{Claim: claim, Err: ErrMissingClaim})
+		}
+	}
+
+	if verified {
+		for _, validator := range p.validators {
+			if err := validator(t.claims); err != nil {
+				errs |= InvalidClaimsError
+				details = append(details, err)
+			}
+		}
+	}
+
+	if errs == 0 {
+		return t, nil
+	}
+
+	if len(details) == 0 || !p.detailedErrors {
+		return t, errs
+	}
+
+	return t, &ParseError{Flags: errs, Errors: details}
+}
+
+// registeredHeaders are the header parameters defined by JWS and JWA, which
+// mustn't be listed in "crit"
+var registeredHeaders = map[string]bool{
+	"alg": true, "jku": true, "jwk": true, "kid": true, "x5u": true, "x5c": true,
+	"x5t": true, "x5t#S256": true, "typ": true, "cty": true, "crit": true,
+	"enc": true, "zip": true, "epk": true, "apu": true, "apv": true, "iv": true,
+	"tag": true, "p2s": true, "p2c": true,
+}
+
+// checkCrit checks the "crit" header. The handlers are only run if verified
+// is true.
+func (p *Parser) checkCrit(t *token, verified bool) []error {
+	crit, ok := t.header["crit"]
+
+	if !ok {
+		return nil
+	}
+
+	names, ok := crit.([]interface{})
+
+	if !ok || len(names) == 0 {
+		return []error{ErrInvalidCritical}
+	}
+
+	var (
+		errs []error
+		seen = make(map[string]bool)
+	)
+
+	for _, elem := range names {
+		name, ok := elem.(string)
+
+		if !ok || seen[name] || registeredHeaders[name] {
+			return []error{ErrInvalidCritical}
+		}
+
+		seen[name] = true
+
+		value, ok := t.header[name]
+
+		if !ok {
+			errs = append(errs, fmt.Errorf("%q: %w", name, ErrInvalidCritical))
+			continue
+		}
+
+		handler, ok := p.critHandlers[name]
+
+		if !ok {
+			errs = append(errs, fmt.Errorf("%q: %w", name, ErrUnsupportedCritical))
+			continue
+		}
+
+		if verified {
+			if err := handler(value, t); err != nil {
+				errs = append(errs, fmt.Errorf("%q: %w", name, err))
+			}
+		}
+	}
+
+	return errs
+}
+
+// timeClaim returns the named claim as a time. present is false if the claim
+// is missing or null, and a *ClaimError is returned if it isn't a NumericDate.
+func timeClaim(claims map[string]interface{}, name string) (v time.Time, present bool, err error) {
+	raw := claims[name]
+
+	if raw == nil {
+		return time.Time{}, false, nil
+	}
+
+	if v, ok := toTime(raw); ok {
+		return v, true, nil
+	}
+
+	return time.Time{}, false, &ClaimError{Claim: name, Err: ErrInvalidTime}
+}
+
+// checkLifetime applies the maximum lifetime and age policies. A missing or
+// invalid "exp" or "iat" claim fails the policies that need it. An "iat" in
+// the future can't be used to extend either limit.
+func (p *Parser) checkLifetime(now time.Time, exp time.Time, hasExp bool, iat time.Time, hasIat bool) (errs ValidationError) {
+	if p.maxLifetime > 0 {
+		issued := iat
+		if !hasIat || iat.After(now) {
+			issued = now
+		}
+
+		if !hasExp || exp.Sub(issued) > p.maxLifetime {
+			errs |= LifetimeExceededError
+		}
+	}
+
+	if p.maxAge > 0 {
+		if !hasIat || iat.After(now) || now.Sub(iat) > p.maxAge {
+			errs |= TooOldError
+		}
+	}
+
+	return
+}
+
+// typeEqual compares two media types as used in the "typ" header, which may
+// omit the "application/" prefix
+func typeEqual(a, b string) bool {
+	const prefix = "application/"
+
+	if len(a) > len(prefix) && strings.EqualFold(a[:len(prefix)], prefix) {
+		a = a[len(prefix):]
+	}
+
+	if len(b) > len(prefix) && strings.EqualFold(b[:len(prefix)], prefix) {
+		b = b[len(prefix):]
+	}
+
+	return a != "" && strings.EqualFold(a, b)
+}
+
+func (p *Parser) decodeSegment(dst []byte, src []byte) ([]byte, error) {
+	if p.strictDecoding {
+		return decodeSegmentStrict(dst, src)
+	}
+
+	return decodeSegment(dst, src)
+}
+
+// unmarshal decodes a JSON header or claims set into v
+func (p *Parser) unmarshal(data []byte, v *map[string]interface{}) error {
+	if p.strictJSON {
+		if err := checkStrictJSON(data); err != nil {
+			return err
+		}
+	}
+
+	if !p.useNumber {
+		return json.Unmarshal(data, v)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+
+	// json.Unmarshal rejects anything following the value, do the same
+	if _, err := dec.Token(); err != io.EOF {
+		return errTrailingData
+	}
+
+	return nil
+}
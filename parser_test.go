@@ -0,0 +1,381 @@
+package jwt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signToken builds a token from the raw JSON header and claims, signing it
+// with HMAC and testKey
+func signToken(t *testing.T, header string, claims string) string {
+	payload := encode([]byte(header)) + "." + encode([]byte(claims))
+	sig, err := HMAC.Sign(payload, testKey)
+
+	if err != nil {
+		t.Fatalf("Error while signing token: %v", err)
+	}
+
+	return payload + "." + sig
+}
+
+func TestParserStrictDecoding(t *testing.T) {
+	p := NewParser(WithStrictDecoding())
+
+	if _, err := p.Parse(testToken, HMAC, testKey); err != nil {
+		t.Errorf("Error occured parsing the token: %v", err)
+	}
+
+	// the last character of a 32 byte signature only carries 4 bits, so
+	// setting one of the 2 trailing bits gives a second encoding of it
+	for _, tokenString := range []string{
+		testToken + "=",
+		testToken[:len(testToken)-1] + "F",
+		testToken + "\n",
+	} {
+		if _, err := ParseToken(tokenString, HMAC, testKey); err != nil {
+			t.Errorf("ParseToken failed to parse %q: %v", tokenString, err)
+		}
+
+		if _, err := p.Parse(tokenString, HMAC, testKey); err != ErrTokenMalformed {
+			t.Errorf("Expected ErrTokenMalformed parsing %q but got %v", tokenString, err)
+		}
+	}
+}
+
+func TestParserStrictJSON(t *testing.T) {
+	p := NewParser(WithStrictJSON())
+	header := `{"alg":"HS256","typ":"JWT"}`
+
+	if _, err := p.Parse(signToken(t, header, `{"sub":"a","roles":["a","b"]}`), HMAC, testKey); err != nil {
+		t.Errorf("Error occured parsing the token: %v", err)
+	}
+
+	for _, test := range []struct {
+		header string
+		claims string
+	}{
+		{`{"alg":"HS256","alg":"none"}`, `{}`},
+		{header, `{"sub":"admin","sub":"guest"}`},
+		{header, `{"a":{"b":1,"b":2}}`},
+		{header, "{\"sub\":\"\xff\"}"},
+		{header, `null`},
+	} {
+		tokenString := signToken(t, test.header, test.claims)
+
+		if _, err := p.Parse(tokenString, HMAC, testKey); err != ErrTokenMalformed {
+			t.Errorf("Expected ErrTokenMalformed parsing %s.%s but got %v", test.header, test.claims, err)
+		}
+	}
+}
+
+func TestParserRequiredClaims(t *testing.T) {
+	p := NewParser(WithRequiredClaims("exp", "sub", "realm_access.roles"))
+	header := `{"alg":"HS256","typ":"JWT"}`
+
+	complete := signToken(t, header, `{"exp":4100000000,"sub":"user","realm_access":{"roles":[]}}`)
+	if _, err := p.Parse(complete, HMAC, testKey); err != nil {
+		t.Errorf("Error occured parsing the token: %v", err)
+	}
+
+	incomplete := signToken(t, header, `{"sub":null,"realm_access":{}}`)
+	_, err := p.Parse(incomplete, HMAC, testKey)
+
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("Expected a ParseError but got %v", err)
+	}
+
+	if perr.Flags != MissingClaimError {
+		t.Errorf("Expected MissingClaimError but got %v", perr.Flags)
+	}
+
+	var verr ValidationError
+	if !errors.As(err, &verr) || verr != MissingClaimError {
+		t.Errorf("ParseError did not unwrap to its ValidationError flags")
+	}
+
+	if !errors.Is(err, ErrMissingClaim) {
+		t.Errorf("ParseError did not unwrap to ErrMissingClaim")
+	}
+
+	var missing []string
+	for _, e := range perr.Errors {
+		var cerr *ClaimError
+		if errors.As(e, &cerr) {
+			missing = append(missing, cerr.Claim)
+		}
+	}
+
+	if strings.Join(missing, ",") != "exp,sub,realm_access.roles" {
+		t.Errorf("Expected exp, sub and realm_access.roles to be reported missing but got %v", missing)
+	}
+}
+
+func TestParserLifetime(t *testing.T) {
+	now := time.Now()
+	defer func() { TimeFunc = time.Now }()
+	TimeFunc = func() time.Time { return now }
+
+	p := NewParser(WithMaxLifetime(24*time.Hour), WithMaxAge(time.Hour))
+
+	for _, test := range []struct {
+		claims map[string]interface{}
+		err    ValidationError
+	}{
+		{map[string]interface{}{"iat": now.Unix(), "exp": now.Add(time.Hour).Unix()}, 0},
+		{map[string]interface{}{"iat": now.Add(-30 * time.Minute).Unix(), "exp": now.Add(23 * time.Hour).Unix()}, 0},
+
+		// ten year token
+		{map[string]interface{}{"iat": now.Unix(), "exp": now.AddDate(10, 0, 0).Unix()}, LifetimeExceededError},
+		{map[string]interface{}{"iat": now.Unix()}, LifetimeExceededError},
+		{map[string]interface{}{"iat": now.Add(-2 * time.Hour).Unix(), "exp": now.Add(time.Hour).Unix()}, TooOldError},
+		{map[string]interface{}{"exp": now.Add(time.Hour).Unix()}, TooOldError},
+		{map[string]interface{}{"exp": now.Add(48 * time.Hour).Unix()}, LifetimeExceededError | TooOldError},
+
+		// an iat in the future doesn't shorten the lifetime or the age
+		{map[string]interface{}{"iat": now.AddDate(10, 0, 0).Add(-time.Hour).Unix(), "exp": now.AddDate(10, 0, 0).Unix()}, LifetimeExceededError | TooOldError},
+		{map[string]interface{}{"iat": now.Add(time.Minute).Unix(), "exp": now.Add(time.Hour).Unix()}, TooOldError},
+	} {
+		tok := NewToken(HMAC)
+		for claim, v := range test.claims {
+			tok.SetClaim(claim, v)
+		}
+
+		encoded, err := tok.Encode(testKey)
+
+		if err != nil {
+			t.Fatalf("Error whilst encoding token: %v", err)
+		}
+
+		_, err = p.Parse(encoded, HMAC, testKey)
+
+		if test.err == 0 && err != nil {
+			t.Errorf("Error occured parsing token with claims %v: %v", test.claims, err)
+		} else if test.err != 0 && err != test.err {
+			t.Errorf("Parsing token with claims %v, expected %v but got %v", test.claims, uint32(test.err), err)
+		}
+	}
+}
+
+func TestParserInvalidTimeClaims(t *testing.T) {
+	header := `{"alg":"HS256","typ":"JWT"}`
+
+	for _, test := range []struct {
+		claims string
+		claim  string
+		err    ValidationError
+	}{
+		{`{"exp":"never"}`, "exp", ExpiredError},
+		{`{"exp":true}`, "exp", ExpiredError},
+		{`{"exp":1e300}`, "exp", ExpiredError},
+		{`{"exp":{}}`, "exp", ExpiredError},
+		{`{"nbf":"now"}`, "nbf", NotYetValidError},
+		{`{"nbf":false}`, "nbf", NotYetValidError},
+		{`{"nbf":-1e300}`, "nbf", NotYetValidError},
+		{`{"iat":"today"}`, "iat", InvalidClaimsError},
+		{`{"iat":true}`, "iat", InvalidClaimsError},
+		{`{"iat":1e300}`, "iat", InvalidClaimsError},
+	} {
+		tokenString := signToken(t, header, test.claims)
+
+		// ParseToken only returns the flags
+		if _, err := ParseToken(tokenString, HMAC, testKey); err != test.err {
+			t.Errorf("[%v] ParseToken expected %v but got %v", test.claims, uint32(test.err), err)
+		}
+
+		for _, useNumber := range []bool{true, false} {
+			p := NewParser(WithJSONNumber(useNumber), WithRequiredClaims(test.claim))
+
+			_, err := p.Parse(tokenString, HMAC, testKey)
+
+			var perr *ParseError
+			if !errors.As(err, &perr) || perr.Flags != test.err {
+				t.Errorf("[%v] Expected a ParseError with %v but got %v", test.claims, uint32(test.err), err)
+				continue
+			}
+
+			var cerr *ClaimError
+			if !errors.As(err, &cerr) || cerr.Claim != test.claim || !errors.Is(err, ErrInvalidTime) {
+				t.Errorf("[%v] Expected a ClaimError for %v but got %v", test.claims, test.claim, err)
+			}
+		}
+	}
+
+	// null is the same as a missing claim
+	if _, err := ParseToken(signToken(t, header, `{"exp":null,"nbf":null,"iat":null}`), HMAC, testKey); err != nil {
+		t.Errorf("Error occured parsing token with null time claims: %v", err)
+	}
+
+	// an invalid iat fails the age policy
+	p := NewParser(WithMaxAge(time.Hour))
+
+	_, err := p.Parse(signToken(t, header, `{"iat":"today"}`), HMAC, testKey)
+
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Flags != InvalidClaimsError|TooOldError {
+		t.Errorf("Expected InvalidClaimsError and TooOldError for an invalid iat but got %v", err)
+	}
+}
+
+func TestParserValidators(t *testing.T) {
+	errTenant := errors.New("wrong tenant")
+	errScope := errors.New("missing scope")
+
+	tenant := func(claims map[string]interface{}) error {
+		if claims["tenant"] != "acme" {
+			return errTenant
+		}
+		return nil
+	}
+
+	scope := func(claims map[string]interface{}) error {
+		if claims["scope"] != "read" {
+			return errScope
+		}
+		return nil
+	}
+
+	p := NewParser(WithValidators(tenant), WithValidators(scope))
+	header := `{"alg":"HS256","typ":"JWT"}`
+
+	if _, err := p.Parse(signToken(t, header, `{"tenant":"acme","scope":"read"}`), HMAC, testKey); err != nil {
+		t.Errorf("Error occured parsing the token: %v", err)
+	}
+
+	_, err := p.Parse(signToken(t, header, `{"tenant":"other","exp":1300819380}`), HMAC, testKey)
+
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("Expected a ParseError but got %v", err)
+	}
+
+	if perr.Flags != ExpiredError|InvalidClaimsError {
+		t.Errorf("Expected ExpiredError and InvalidClaimsError but got %v", uint32(perr.Flags))
+	}
+
+	if !errors.Is(err, errTenant) || !errors.Is(err, errScope) {
+		t.Errorf("Validator errors were not aggregated, got %v", err)
+	}
+
+	// validators don't see claims with a bad signature
+	called := false
+	p = NewParser(WithValidators(func(map[string]interface{}) error {
+		called = true
+		return nil
+	}))
+
+	if _, err := p.Parse(testToken, HMAC, "wrong-key"); err != BadSignatureError {
+		t.Errorf("Expected BadSignatureError but got %v", err)
+	}
+
+	if called {
+		t.Errorf("Validator was run on a token with a bad signature")
+	}
+}
+
+func TestParserExpectedType(t *testing.T) {
+	p := NewParser(WithExpectedType("at+jwt"))
+
+	for _, test := range []struct {
+		typ   interface{}
+		valid bool
+	}{
+		{"at+jwt", true},
+		{"AT+JWT", true},
+		{"application/at+jwt", true},
+		{"Application/AT+JWT", true},
+		{"JWT", false},
+		{"secevent+jwt", false},
+		{"application/", false},
+		{1, false},
+		{nil, false},
+	} {
+		tok := NewToken(HMAC).(HeaderToken)
+		tok.SetHeader("typ", test.typ)
+
+		encoded, err := tok.Encode(testKey)
+
+		if err != nil {
+			t.Fatalf("Error whilst encoding token: %v", err)
+		}
+
+		_, err = p.Parse(encoded, HMAC, testKey)
+
+		if test.valid && err != nil {
+			t.Errorf("Error occured parsing token with typ %v: %v", test.typ, err)
+		} else if !test.valid && err != InvalidTypeError {
+			t.Errorf("Parsing token with typ %v, expected InvalidTypeError but got %v", test.typ, err)
+		}
+	}
+
+	if typeEqual("application/jwt", "application/") {
+		t.Errorf("typeEqual matched an empty type")
+	}
+
+	if !typeEqual("JWT", "application/jwt") {
+		t.Errorf("typeEqual did not strip the application/ prefix from the expected type")
+	}
+}
+
+func TestParserCrit(t *testing.T) {
+	errExpired := errors.New("session expired")
+
+	var seen interface{}
+	p := NewParser(WithCritHandler("exp", func(value interface{}, tok Token) error {
+		seen = value
+		if value != "ok" {
+			return errExpired
+		}
+		return nil
+	}))
+
+	if _, err := p.Parse(signToken(t, `{"alg":"HS256","crit":["exp"],"exp":"ok"}`, `{}`), HMAC, testKey); err != nil {
+		t.Errorf("Error occured parsing the token: %v", err)
+	}
+
+	if seen != "ok" {
+		t.Errorf("Crit handler was not called with the header value, got %v", seen)
+	}
+
+	for _, test := range []struct {
+		header string
+		err    error
+	}{
+		{`{"alg":"HS256","crit":["exp"],"exp":"stale"}`, errExpired},
+		{`{"alg":"HS256","crit":["b64"],"b64":false}`, ErrUnsupportedCritical},
+		{`{"alg":"HS256","crit":["exp","b64"],"exp":"ok","b64":false}`, ErrUnsupportedCritical},
+		{`{"alg":"HS256","crit":["exp"]}`, ErrInvalidCritical},
+		{`{"alg":"HS256","crit":[],"exp":"ok"}`, ErrInvalidCritical},
+		{`{"alg":"HS256","crit":"exp","exp":"ok"}`, ErrInvalidCritical},
+		{`{"alg":"HS256","crit":["exp","exp"],"exp":"ok"}`, ErrInvalidCritical},
+		{`{"alg":"HS256","crit":["alg"]}`, ErrInvalidCritical},
+	} {
+		_, err := p.Parse(signToken(t, test.header, `{}`), HMAC, testKey)
+
+		var perr *ParseError
+		if !errors.As(err, &perr) || perr.Flags != CriticalHeaderError {
+			t.Errorf("Parsing token with header %s, expected CriticalHeaderError but got %v", test.header, err)
+		}
+
+		if !errors.Is(err, test.err) {
+			t.Errorf("Parsing token with header %s, expected %v but got %v", test.header, test.err, err)
+		}
+	}
+
+	// ParseToken understands no extensions, and only returns the flags
+	_, err := ParseToken(signToken(t, `{"alg":"HS256","crit":["exp"],"exp":"ok"}`, `{}`), HMAC, testKey)
+
+	if err != CriticalHeaderError {
+		t.Errorf("Expected ParseToken to reject crit with CriticalHeaderError but got %v", err)
+	}
+
+	// an unverified header gets no details
+	forged := signToken(t, `{"alg":"HS256","crit":["b64"],"b64":false}`, `{}`)
+	forged = forged[:strings.LastIndexByte(forged, '.')+1] + "AAAA"
+
+	if _, err := p.Parse(forged, HMAC, testKey); err != BadSignatureError|CriticalHeaderError {
+		t.Errorf("Expected only the flags for a bad signature but got %v", err)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build race
+
+package jwt
+
+// raceEnabled reports whether the race detector is enabled, which adds
+// allocations
+const raceEnabled = true
@@ -7,6 +7,7 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
+	"hash"
 )
 
 // SigningAlgorithmRSA represents an RSA signing algorithm
@@ -19,7 +20,7 @@ type SigningAlgorithmRSA struct {
 var (
 	RSA   = &SigningAlgorithmRSA{"RS256", crypto.SHA256}
 	RS256 = &SigningAlgorithmRSA{"RS256", crypto.SHA256}
-	RS384 = &SigningAlgorithmRSA{"RS256", crypto.SHA384}
+	RS384 = &SigningAlgorithmRSA{"RS384", crypto.SHA384}
 	RS512 = &SigningAlgorithmRSA{"RS512", crypto.SHA512}
 )
 
@@ -28,10 +29,95 @@ func (alg *SigningAlgorithmRSA) Name() string {
 	return alg.name
 }
 
-func (alg *SigningAlgorithmRSA) sign(payload string, key interface{}) ([]byte, error) {
+// rsaKeyPair holds the parsed keys of a PreparedKey. private is nil if the
+// key was prepared from a public key.
+type rsaKeyPair struct {
+	private *rsa.PrivateKey
+	public  *rsa.PublicKey
+}
+
+// PrepareKey parses the key once so that it can be reused across calls to
+// Sign and Verify. The key may be an *rsa.PrivateKey, an *rsa.PublicKey,
+// either of them wrapped in an RSAPrivateKey or RSAPublicKey, or a string or
+// byte array containing either of them PEM encoded. A prepared
+// private key can be used to both sign and verify.
+func (alg *SigningAlgorithmRSA) PrepareKey(key interface{}) (*PreparedKey, error) {
+	var (
+		keys = &rsaKeyPair{}
+		err  error
+	)
+
+	switch k := key.(type) {
+	case string:
+		keys, err = parseRSAKeyPairFromPEM([]byte(k))
+	case []byte:
+		keys, err = parseRSAKeyPairFromPEM(k)
+	case *rsa.PrivateKey:
+		if k == nil {
+			return nil, ErrInvalidKey
+		}
+
+		keys.private = k
+		keys.public = &k.PublicKey
+	case *rsa.PublicKey:
+		keys.public = k
+	case RSAPrivateKey:
+		if !allowsAlgorithm(k.Algorithms, alg) {
+			return nil, ErrAlgorithmMismatch
+		}
+
+		if k.Key == nil {
+			return nil, ErrInvalidKey
+		}
+
+		keys.private = k.Key
+		keys.public = &k.Key.PublicKey
+	case RSAPublicKey:
+		if !allowsAlgorithm(k.Algorithms, alg) {
+			return nil, ErrAlgorithmMismatch
+		}
+
+		keys.public = k.Key
+	default:
+		return nil, invalidKey(key)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if keys.public == nil {
+		return nil, ErrInvalidKey
+	}
+
+	if keys.public.N.BitLen() < MinRSAKeySize {
+		return nil, ErrWeakKey
+	}
+
+	hashFunc, err := newHashFunc(alg.hash)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &PreparedKey{hash: alg.hash, hashFunc: hashFunc, key: keys}, nil
+}
+
+// prepared returns the key pair held by a PreparedKey if it was prepared for
+// this algorithm
+func (alg *SigningAlgorithmRSA) prepared(pk *PreparedKey) (*rsaKeyPair, error) {
+	if keys, ok := pk.key.(*rsaKeyPair); ok && pk.hash == alg.hash {
+		return keys, nil
+	}
+
+	return nil, ErrInvalidKey
+}
+
+func (alg *SigningAlgorithmRSA) sign(payload []byte, key interface{}) ([]byte, error) {
 	var (
-		rsaKey *rsa.PrivateKey
-		err    error
+		rsaKey   *rsa.PrivateKey
+		hashFunc func() hash.Hash
+		err      error
 	)
 
 	switch k := key.(type) {
@@ -45,29 +131,52 @@ func (alg *SigningAlgorithmRSA) sign(payload string, key interface{}) ([]byte, e
 		}
 	case *rsa.PrivateKey:
 		rsaKey = k
+	case RSAPrivateKey:
+		if !allowsAlgorithm(k.Algorithms, alg) {
+			return nil, ErrAlgorithmMismatch
+		}
+
+		rsaKey = k.Key
+	case *PreparedKey:
+		var keys *rsaKeyPair
+		if keys, err = alg.prepared(k); err != nil {
+			return nil, err
+		}
+
+		if keys.private == nil {
+			return nil, ErrNotRSAPrivateKey
+		}
+
+		rsaKey = keys.private
+		hashFunc = k.hashFunc
 	default:
+		return nil, invalidKey(key)
+	}
+
+	if rsaKey == nil {
 		return nil, ErrInvalidKey
 	}
 
-	hashFunc, err := newHashFunc(alg.hash)
+	if rsaKey.N.BitLen() < MinRSAKeySize {
+		return nil, ErrWeakKey
+	}
 
-	if err != nil {
-		return nil, err
+	if hashFunc == nil {
+		if hashFunc, err = newHashFunc(alg.hash); err != nil {
+			return nil, err
+		}
 	}
 
 	hasher := hashFunc()
-	hasher.Write([]byte(payload))
+	hasher.Write(payload)
 
 	// Sign the string and return the encoded bytes
-	if sigBytes, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, alg.hash, hasher.Sum(nil)); err == nil {
-		return sigBytes, nil
-	}
-
-	return nil, err
+	return rsa.SignPKCS1v15(rand.Reader, rsaKey, alg.hash, hasher.Sum(nil))
 }
 
-// Sign takes a string payload and a key as either an rsa.PrivateKey or a string or
-// byte array containing a PEM encoded key.
+// Sign takes a string payload and a key as either an rsa.PrivateKey, an
+// RSAPrivateKey, a PreparedKey or a string or byte array containing a PEM
+// encoded key.
 // Either returns the signature as a string or an error.
 func (alg *SigningAlgorithmRSA) Sign(payload string, key interface{}) (string, error) {
 	var (
@@ -75,17 +184,22 @@ func (alg *SigningAlgorithmRSA) Sign(payload string, key interface{}) (string, e
 		err      error
 	)
 
-	if sigBytes, err = alg.sign(payload, key); err == nil {
+	if sigBytes, err = alg.sign([]byte(payload), key); err == nil {
 		return encode(sigBytes), nil
 	}
 
 	return "", err
 }
 
+// SignBytes is like Sign but works on byte slices and returns the raw
+// signature
+func (alg *SigningAlgorithmRSA) SignBytes(payload []byte, key interface{}) ([]byte, error) {
+	return alg.sign(payload, key)
+}
+
 // Verify checks that the signature is valid
 func (alg *SigningAlgorithmRSA) Verify(payload string, signature string, key interface{}) error {
 	var (
-		rsaKey   *rsa.PublicKey
 		sigBytes []byte
 		err      error
 	)
@@ -95,6 +209,18 @@ func (alg *SigningAlgorithmRSA) Verify(payload string, signature string, key int
 		return err
 	}
 
+	return alg.VerifyBytes([]byte(payload), sigBytes, key)
+}
+
+// VerifyBytes is like Verify but works on byte slices and takes the raw
+// signature
+func (alg *SigningAlgorithmRSA) VerifyBytes(payload []byte, signature []byte, key interface{}) error {
+	var (
+		rsaKey   *rsa.PublicKey
+		hashFunc func() hash.Hash
+		err      error
+	)
+
 	switch k := key.(type) {
 	case string:
 		if rsaKey, err = ParseRSAPublicKeyFromPEM([]byte(k)); err != nil {
@@ -106,20 +232,52 @@ func (alg *SigningAlgorithmRSA) Verify(payload string, signature string, key int
 		}
 	case *rsa.PublicKey:
 		rsaKey = k
+	case RSAPublicKey:
+		if !allowsAlgorithm(k.Algorithms, alg) {
+			return ErrAlgorithmMismatch
+		}
+
+		rsaKey = k.Key
+	case RSAPrivateKey:
+		if !allowsAlgorithm(k.Algorithms, alg) {
+			return ErrAlgorithmMismatch
+		}
+
+		if k.Key == nil {
+			return ErrInvalidKey
+		}
+
+		rsaKey = &k.Key.PublicKey
+	case *PreparedKey:
+		var keys *rsaKeyPair
+		if keys, err = alg.prepared(k); err != nil {
+			return err
+		}
+
+		rsaKey = keys.public
+		hashFunc = k.hashFunc
 	default:
+		return invalidKey(key)
+	}
+
+	if rsaKey == nil {
 		return ErrInvalidKey
 	}
 
-	hashFunc, err := newHashFunc(alg.hash)
+	if rsaKey.N.BitLen() < MinRSAKeySize {
+		return ErrWeakKey
+	}
 
-	if err != nil {
-		return err
+	if hashFunc == nil {
+		if hashFunc, err = newHashFunc(alg.hash); err != nil {
+			return err
+		}
 	}
 
 	hasher := hashFunc()
-	hasher.Write([]byte(payload))
+	hasher.Write(payload)
 
-	return rsa.VerifyPKCS1v15(rsaKey, alg.hash, hasher.Sum(nil), sigBytes)
+	return rsa.VerifyPKCS1v15(rsaKey, alg.hash, hasher.Sum(nil), signature)
 }
 
 // Errors relating to parsing PEMs
@@ -182,3 +340,19 @@ func ParseRSAPublicKeyFromPEM(key []byte) (*rsa.PublicKey, error) {
 
 	return pkey, nil
 }
+
+// parseRSAKeyPairFromPEM decodes a PEM encoded private key, falling back to a
+// public key if the PEM doesn't hold a private key
+func parseRSAKeyPairFromPEM(key []byte) (*rsaKeyPair, error) {
+	if private, err := ParseRSAPrivateKeyFromPEM(key); err == nil {
+		return &rsaKeyPair{private: private, public: &private.PublicKey}, nil
+	}
+
+	public, err := ParseRSAPublicKeyFromPEM(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &rsaKeyPair{public: public}, nil
+}
@@ -1,6 +1,8 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
 	"strings"
 	"testing"
 )
@@ -128,3 +130,142 @@ func TestRS512Sign(t *testing.T) {
 func TestRS512Verify(t *testing.T) {
 	testRSAVerify(t, rs512Test, RS512)
 }
+
+func TestRSAName(t *testing.T) {
+	for _, test := range []struct {
+		alg  *SigningAlgorithmRSA
+		name string
+	}{
+		{RSA, "RS256"},
+		{RS256, "RS256"},
+		{RS384, "RS384"},
+		{RS512, "RS512"},
+	} {
+		if name := test.alg.Name(); name != test.name {
+			t.Errorf("[%v] Expected name %v but got %v", test.name, test.name, name)
+		}
+
+		if alg := NewToken(test.alg).(HeaderToken).Header("alg"); alg != test.name {
+			t.Errorf("[%v] Expected alg header %v but got %v", test.name, test.name, alg)
+		}
+	}
+}
+
+func TestRSAPreparedKey(t *testing.T) {
+	segments := strings.Split(rs256Test, ".")
+
+	privateKey, err := RS256.PrepareKey(rsaPrivateKey)
+
+	if err != nil {
+		t.Fatalf("Error while preparing private key: %v", err)
+	}
+
+	sig, err := RS256.Sign(strings.Join(segments[0:2], "."), privateKey)
+
+	if err != nil {
+		t.Errorf("Error while signing token: %v", err)
+	}
+
+	if sig != segments[2] {
+		t.Errorf("Incorrect signature.\nwas:\n%v\nexpecting:\n%v", sig, segments[2])
+	}
+
+	publicKey, err := RS256.PrepareKey([]byte(rsaPublicKey))
+
+	if err != nil {
+		t.Fatalf("Error while preparing public key: %v", err)
+	}
+
+	for _, key := range []*PreparedKey{privateKey, publicKey} {
+		if err := RS256.Verify(strings.Join(segments[0:2], "."), segments[2], key); err != nil {
+			t.Errorf("Error while verifying signature: %v", err)
+		}
+	}
+
+	if _, err := RS256.Sign(strings.Join(segments[0:2], "."), publicKey); err == nil {
+		t.Errorf("Signed using a prepared public key")
+	}
+
+	if err := RS512.Verify(strings.Join(segments[0:2], "."), segments[2], publicKey); err != ErrInvalidKey {
+		t.Errorf("Expected ErrInvalidKey using key prepared for another algorithm but got %v", err)
+	}
+}
+
+func benchmarkRSAVerify(b *testing.B, key interface{}) {
+	segments := strings.Split(rs256Test, ".")
+	payload := strings.Join(segments[0:2], ".")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := RS256.Verify(payload, segments[2], key); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRS256VerifyPEM(b *testing.B) {
+	benchmarkRSAVerify(b, rsaPublicKey)
+}
+
+func BenchmarkRS256VerifyPrepared(b *testing.B) {
+	key, err := RS256.PrepareKey(rsaPublicKey)
+
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarkRSAVerify(b, key)
+}
+
+func TestRSAMinKeySize(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig, err := RS256.Sign("payload", key)
+
+	if err != ErrWeakKey {
+		t.Errorf("Expected ErrWeakKey signing with a 1024 bit key but got %v", err)
+	}
+
+	if _, err := RS256.PrepareKey(&key.PublicKey); err != ErrWeakKey {
+		t.Errorf("Expected ErrWeakKey preparing a 1024 bit key but got %v", err)
+	}
+
+	defer func(size int) { MinRSAKeySize = size }(MinRSAKeySize)
+	MinRSAKeySize = 1024
+
+	if sig, err = RS256.Sign("payload", key); err != nil {
+		t.Fatalf("Error while signing with a lowered minimum: %v", err)
+	}
+
+	if err := RS256.Verify("payload", sig, &key.PublicKey); err != nil {
+		t.Errorf("Error while verifying with a lowered minimum: %v", err)
+	}
+
+	MinRSAKeySize = 2048
+
+	if err := RS256.Verify("payload", sig, &key.PublicKey); err != ErrWeakKey {
+		t.Errorf("Expected ErrWeakKey verifying with a 1024 bit key but got %v", err)
+	}
+}
+
+func TestRSANilKeys(t *testing.T) {
+	for _, key := range []interface{}{RSAPrivateKey{}, RSAPublicKey{}, (*rsa.PrivateKey)(nil), (*rsa.PublicKey)(nil)} {
+		if err := RS256.VerifyBytes([]byte("payload"), []byte("signature"), key); err != ErrInvalidKey {
+			t.Errorf("Verifying with %#v, expected ErrInvalidKey but got %v", key, err)
+		}
+
+		if _, err := RS256.PrepareKey(key); err != ErrInvalidKey {
+			t.Errorf("Preparing %#v, expected ErrInvalidKey but got %v", key, err)
+		}
+	}
+
+	for _, key := range []interface{}{RSAPrivateKey{}, (*rsa.PrivateKey)(nil)} {
+		if _, err := RS256.SignBytes([]byte("payload"), key); err != ErrInvalidKey {
+			t.Errorf("Signing with %#v, expected ErrInvalidKey but got %v", key, err)
+		}
+	}
+}
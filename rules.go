@@ -0,0 +1,510 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidRule is returned when a rule's configuration is invalid
+var ErrInvalidRule = errors.New("The rule configuration is invalid")
+
+// Rule is a predicate over the claims of a token. Rules compose with All and
+// Any and are evaluated during parsing by adding them to a Parser with
+// WithRules. Claim names may be dotted paths as accepted by the typed claim
+// accessors. Rules may also be built from configuration with ParseRule.
+type Rule interface {
+	// Validate returns a *RuleError, or for All several joined together, if
+	// the claims don't satisfy the rule
+	Validate(claims map[string]interface{}) error
+	String() string
+}
+
+// RuleError describes which predicate of a Rule failed and why
+type RuleError struct {
+	Rule   Rule
+	Reason string
+}
+
+func (e *RuleError) Error() string {
+	return e.Rule.String() + ": " + e.Reason
+}
+
+// WithRules makes the Parser evaluate the rules, as validators, after the
+// signature and time checks. Each rule that fails sets InvalidClaimsError and
+// its RuleError is included in the ParseError.
+func WithRules(rules ...Rule) ParserOption {
+	return func(p *Parser) {
+		for _, rule := range rules {
+			p.validators = append(p.validators, rule.Validate)
+		}
+	}
+}
+
+type equalsRule struct {
+	claim string
+	value interface{}
+}
+
+// Equals requires the claim to equal value. Numbers are compared by value
+// whether they were decoded as float64 or json.Number.
+func Equals(claim string, value interface{}) Rule {
+	return &equalsRule{claim, value}
+}
+
+func (r *equalsRule) Validate(claims map[string]interface{}) error {
+	v, ok := lookupClaim(claims, r.claim)
+
+	if !ok {
+		return &RuleError{r, "claim is missing"}
+	}
+
+	if !claimEqual(v, r.value) {
+		return &RuleError{r, "claim is " + formatValue(v)}
+	}
+
+	return nil
+}
+
+func (r *equalsRule) String() string {
+	return fmt.Sprintf("Equals(%q, %s)", r.claim, formatValue(r.value))
+}
+
+type containsRule struct {
+	claim string
+	value interface{}
+}
+
+// Contains requires the claim, which must be an array, to contain value. If
+// the claim is a string it's treated as a space separated list, as used by
+// the OAuth "scope" claim.
+func Contains(claim string, value interface{}) Rule {
+	return &containsRule{claim, value}
+}
+
+func (r *containsRule) Validate(claims map[string]interface{}) error {
+	v, ok := lookupClaim(claims, r.claim)
+
+	if !ok {
+		return &RuleError{r, "claim is missing"}
+	}
+
+	var elems []interface{}
+
+	switch c := v.(type) {
+	case []interface{}:
+		elems = c
+	case []string:
+		for _, s := range c {
+			elems = append(elems, s)
+		}
+	case string:
+		for _, s := range strings.Fields(c) {
+			elems = append(elems, s)
+		}
+	default:
+		return &RuleError{r, "claim is not an array or string"}
+	}
+
+	for _, elem := range elems {
+		if claimEqual(elem, r.value) {
+			return nil
+		}
+	}
+
+	return &RuleError{r, "claim is " + formatValue(v)}
+}
+
+func (r *containsRule) String() string {
+	return fmt.Sprintf("Contains(%q, %s)", r.claim, formatValue(r.value))
+}
+
+type oneOfRule struct {
+	claim  string
+	values []interface{}
+}
+
+// OneOf requires the claim to equal one of values
+func OneOf(claim string, values ...interface{}) Rule {
+	return &oneOfRule{claim, values}
+}
+
+func (r *oneOfRule) Validate(claims map[string]interface{}) error {
+	v, ok := lookupClaim(claims, r.claim)
+
+	if !ok {
+		return &RuleError{r, "claim is missing"}
+	}
+
+	for _, value := range r.values {
+		if claimEqual(v, value) {
+			return nil
+		}
+	}
+
+	return &RuleError{r, "claim is " + formatValue(v)}
+}
+
+func (r *oneOfRule) String() string {
+	values := make([]string, len(r.values))
+	for i, value := range r.values {
+		values[i] = formatValue(value)
+	}
+
+	return fmt.Sprintf("OneOf(%q, %s)", r.claim, strings.Join(values, ", "))
+}
+
+type matchesRule struct {
+	claim string
+	re    *regexp.Regexp
+}
+
+// Matches requires the claim to be a string matched by re. The expression
+// isn't anchored, use ^ and $ to match the whole claim. If re is nil the rule
+// fails for every token.
+func Matches(claim string, re *regexp.Regexp) Rule {
+	return &matchesRule{claim, re}
+}
+
+// MatchesPattern is like Matches but compiles pattern, which uses the syntax
+// accepted by regexp.Compile
+func MatchesPattern(claim string, pattern string) (Rule, error) {
+	re, err := regexp.Compile(pattern)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+
+	return Matches(claim, re), nil
+}
+
+func (r *matchesRule) Validate(claims map[string]interface{}) error {
+	if r.re == nil {
+		return &RuleError{r, "the expression is nil"}
+	}
+
+	v, ok := lookupClaim(claims, r.claim)
+
+	if !ok {
+		return &RuleError{r, "claim is missing"}
+	}
+
+	s, ok := v.(string)
+
+	if !ok {
+		return &RuleError{r, "claim is not a string"}
+	}
+
+	if !r.re.MatchString(s) {
+		return &RuleError{r, "claim is " + formatValue(s)}
+	}
+
+	return nil
+}
+
+func (r *matchesRule) String() string {
+	if r.re == nil {
+		return fmt.Sprintf("Matches(%q, nil)", r.claim)
+	}
+
+	return fmt.Sprintf("Matches(%q, %q)", r.claim, r.re.String())
+}
+
+type existsRule struct {
+	claims []string
+}
+
+// Exists requires each of the claims to be present and not null
+func Exists(claims ...string) Rule {
+	return &existsRule{claims}
+}
+
+func (r *existsRule) Validate(claims map[string]interface{}) error {
+	var missing []string
+
+	for _, claim := range r.claims {
+		if v, _ := lookupClaim(claims, claim); v == nil {
+			missing = append(missing, fmt.Sprintf("%q", claim))
+		}
+	}
+
+	if len(missing) > 0 {
+		return &RuleError{r, "missing " + strings.Join(missing, ", ")}
+	}
+
+	return nil
+}
+
+func (r *existsRule) String() string {
+	claims := make([]string, len(r.claims))
+	for i, claim := range r.claims {
+		claims[i] = fmt.Sprintf("%q", claim)
+	}
+
+	return "Exists(" + strings.Join(claims, ", ") + ")"
+}
+
+type allRule struct {
+	rules []Rule
+}
+
+// All requires every one of the rules to pass. The errors from all the
+// failing rules are returned, joined together.
+func All(rules ...Rule) Rule {
+	return &allRule{rules}
+}
+
+func (r *allRule) Validate(claims map[string]interface{}) error {
+	var errs []error
+
+	for _, rule := range r.rules {
+		if err := rule.Validate(claims); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 1 {
+		return errs[0]
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r *allRule) String() string {
+	return "All(" + joinRules(r.rules) + ")"
+}
+
+type anyRule struct {
+	rules []Rule
+}
+
+// Any requires at least one of the rules to pass. If none do, the reason
+// lists why each of them failed.
+func Any(rules ...Rule) Rule {
+	return &anyRule{rules}
+}
+
+func (r *anyRule) Validate(claims map[string]interface{}) error {
+	reasons := make([]string, 0, len(r.rules))
+
+	for _, rule := range r.rules {
+		err := rule.Validate(claims)
+
+		if err == nil {
+			return nil
+		}
+
+		reasons = append(reasons, err.Error())
+	}
+
+	return &RuleError{r, "no rule passed: " + strings.Join(reasons, "; ")}
+}
+
+func (r *anyRule) String() string {
+	return "Any(" + joinRules(r.rules) + ")"
+}
+
+// ParseRule parses a rule from JSON, so that rules can be kept in
+// configuration rather than code. The format is described by RuleFromConfig.
+// Duplicate member names are rejected and numbers keep their precision.
+func ParseRule(data []byte) (Rule, error) {
+	if err := checkStrictJSON(data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var config interface{}
+	if err := dec.Decode(&config); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+
+	return RuleFromConfig(config)
+}
+
+// RuleFromConfig builds a rule from configuration that has already been
+// decoded, for example from JSON or YAML. A rule is an object with a single
+// member naming the rule, whose value holds its arguments:
+//
+//	{"equals": {"claim": "iss", "value": "https://issuer.example.com"}}
+//	{"contains": {"claim": "scope", "value": "read"}}
+//	{"oneOf": {"claim": "tenant", "values": ["acme", "globex"]}}
+//	{"matches": {"claim": "sub", "pattern": "^user-[0-9]+$"}}
+//	{"exists": ["sub", "realm_access.roles"]}
+//	{"all": [rule, ...]}
+//	{"any": [rule, ...]}
+func RuleFromConfig(config interface{}) (Rule, error) {
+	obj, ok := config.(map[string]interface{})
+
+	if !ok || len(obj) != 1 {
+		return nil, fmt.Errorf("%w: a rule must be an object with one member", ErrInvalidRule)
+	}
+
+	var (
+		name string
+		args interface{}
+	)
+
+	// obj has exactly one member
+	for name, args = range obj {
+	}
+
+	switch name {
+	case "equals", "contains":
+		claim, value, err := ruleArgs(name, args, "value")
+
+		if err != nil {
+			return nil, err
+		}
+
+		if name == "equals" {
+			return Equals(claim, value), nil
+		}
+
+		return Contains(claim, value), nil
+	case "oneOf":
+		claim, value, err := ruleArgs(name, args, "values")
+
+		if err != nil {
+			return nil, err
+		}
+
+		values, ok := value.([]interface{})
+
+		if !ok {
+			return nil, fmt.Errorf("%w: %q values must be an array", ErrInvalidRule, name)
+		}
+
+		return OneOf(claim, values...), nil
+	case "matches":
+		claim, value, err := ruleArgs(name, args, "pattern")
+
+		if err != nil {
+			return nil, err
+		}
+
+		pattern, ok := value.(string)
+
+		if !ok {
+			return nil, fmt.Errorf("%w: %q pattern must be a string", ErrInvalidRule, name)
+		}
+
+		return MatchesPattern(claim, pattern)
+	case "exists":
+		elems, ok := args.([]interface{})
+
+		if !ok {
+			return nil, fmt.Errorf("%w: %q must be an array of claim names", ErrInvalidRule, name)
+		}
+
+		claims := make([]string, len(elems))
+
+		for i, elem := range elems {
+			if claims[i], ok = elem.(string); !ok || claims[i] == "" {
+				return nil, fmt.Errorf("%w: %q must be an array of claim names", ErrInvalidRule, name)
+			}
+		}
+
+		return Exists(claims...), nil
+	case "all", "any":
+		elems, ok := args.([]interface{})
+
+		if !ok {
+			return nil, fmt.Errorf("%w: %q must be an array of rules", ErrInvalidRule, name)
+		}
+
+		rules := make([]Rule, len(elems))
+
+		for i, elem := range elems {
+			var err error
+			if rules[i], err = RuleFromConfig(elem); err != nil {
+				return nil, err
+			}
+		}
+
+		if name == "all" {
+			return All(rules...), nil
+		}
+
+		return Any(rules...), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown rule %q", ErrInvalidRule, name)
+	}
+}
+
+// ruleArgs returns the arguments of a rule that takes a claim name and one
+// other member
+func ruleArgs(name string, args interface{}, valueName string) (claim string, value interface{}, err error) {
+	obj, ok := args.(map[string]interface{})
+
+	if !ok || len(obj) != 2 {
+		return "", nil, fmt.Errorf("%w: %q must have \"claim\" and %q members", ErrInvalidRule, name, valueName)
+	}
+
+	if claim, ok = obj["claim"].(string); !ok || claim == "" {
+		return "", nil, fmt.Errorf("%w: %q claim must be a non-empty string", ErrInvalidRule, name)
+	}
+
+	if value, ok = obj[valueName]; !ok {
+		return "", nil, fmt.Errorf("%w: %q must have \"claim\" and %q members", ErrInvalidRule, name, valueName)
+	}
+
+	return claim, value, nil
+}
+
+func joinRules(rules []Rule) string {
+	s := make([]string, len(rules))
+	for i, rule := range rules {
+		s[i] = rule.String()
+	}
+
+	return strings.Join(s, ", ")
+}
+
+// claimEqual reports whether the claim v equals value, comparing numbers by
+// value regardless of how they were decoded
+func claimEqual(v interface{}, value interface{}) bool {
+	if a, ok := toInt64(v); ok {
+		b, ok := toInt64(value)
+		return ok && a == b
+	}
+
+	if a, ok := toFloat64(v); ok {
+		b, ok := toFloat64(value)
+		return ok && a == b
+	}
+
+	return reflect.DeepEqual(v, value)
+}
+
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	}
+
+	if i, ok := toInt64(v); ok {
+		return float64(i), true
+	}
+
+	return 0, false
+}
+
+// formatValue formats v for use in a rule description
+func formatValue(v interface{}) string {
+	if b, err := json.Marshal(v); err == nil {
+		return string(b)
+	}
+
+	return fmt.Sprintf("%v", v)
+}
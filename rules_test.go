@@ -0,0 +1,212 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var ruleClaims = map[string]interface{}{
+	"iss":    "https://issuer.example.com",
+	"sub":    "user-42",
+	"tenant": "acme",
+	"scope":  "read write",
+	"aud":    []interface{}{"api", "web"},
+	"level":  json.Number("3"),
+	"admin":  false,
+	"realm_access": map[string]interface{}{
+		"roles": []interface{}{"offline_access", "admin"},
+	},
+}
+
+func TestRules(t *testing.T) {
+	for _, test := range []struct {
+		rule  Rule
+		valid bool
+	}{
+		{Equals("iss", "https://issuer.example.com"), true},
+		{Equals("iss", "https://other.example.com"), false},
+		{Equals("level", 3), true},
+		{Equals("level", 3.0), true},
+		{Equals("level", "3"), false},
+		{Equals("admin", false), true},
+		{Equals("missing", nil), false},
+
+		{Contains("scope", "read"), true},
+		{Contains("scope", "rea"), false},
+		{Contains("aud", "web"), true},
+		{Contains("aud", "admin"), false},
+		{Contains("realm_access.roles", "admin"), true},
+		{Contains("level", 3), false},
+
+		{OneOf("tenant", "acme", "globex"), true},
+		{OneOf("tenant", "globex", "initech"), false},
+		{OneOf("level", 1, 2, 3), true},
+
+		{Matches("sub", regexp.MustCompile(`^user-\d+$`)), true},
+		{Matches("sub", regexp.MustCompile(`^admin-`)), false},
+		{Matches("level", regexp.MustCompile(`3`)), false},
+
+		{Exists("iss", "sub", "realm_access.roles"), true},
+		{Exists("iss", "jti"), false},
+
+		{All(), true},
+		{All(Equals("tenant", "acme"), Contains("scope", "read")), true},
+		{All(Equals("tenant", "acme"), Contains("scope", "delete")), false},
+		{Any(), false},
+		{Any(Equals("tenant", "globex"), Contains("scope", "read")), true},
+		{Any(Equals("tenant", "globex"), Contains("scope", "delete")), false},
+		{Any(All(Equals("admin", true)), All(Equals("tenant", "acme"), Exists("sub"))), true},
+	} {
+		err := test.rule.Validate(ruleClaims)
+
+		if test.valid && err != nil {
+			t.Errorf("%v failed: %v", test.rule, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("%v passed", test.rule)
+		}
+	}
+}
+
+func TestRuleErrors(t *testing.T) {
+	rule := All(
+		Equals("iss", "https://issuer.example.com"),
+		Contains("scope", "delete"),
+		Any(OneOf("tenant", "globex"), Exists("jti")),
+	)
+
+	if s := rule.String(); s != `All(Equals("iss", "https://issuer.example.com"), Contains("scope", "delete"), Any(OneOf("tenant", "globex"), Exists("jti")))` {
+		t.Errorf("Unexpected rule description %v", s)
+	}
+
+	err := rule.Validate(ruleClaims)
+
+	var rerr *RuleError
+	if !errors.As(err, &rerr) || rerr.Rule.String() != `Contains("scope", "delete")` {
+		t.Errorf("Expected the Contains rule to fail but got %v", err)
+	}
+
+	for _, msg := range []string{
+		`Contains("scope", "delete"): claim is "read write"`,
+		`Any(OneOf("tenant", "globex"), Exists("jti")): no rule passed: OneOf("tenant", "globex"): claim is "acme"; Exists("jti"): missing "jti"`,
+	} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("Expected error to contain %q but got %v", msg, err)
+		}
+	}
+}
+
+func TestParserRules(t *testing.T) {
+	p := NewParser(WithRules(Equals("tenant", "acme"), Contains("scope", "read")))
+	header := `{"alg":"HS256","typ":"JWT"}`
+
+	if _, err := p.Parse(signToken(t, header, `{"tenant":"acme","scope":"read write"}`), HMAC, testKey); err != nil {
+		t.Errorf("Error occured parsing the token: %v", err)
+	}
+
+	_, err := p.Parse(signToken(t, header, `{"tenant":"globex","scope":"read"}`), HMAC, testKey)
+
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Flags != InvalidClaimsError || len(perr.Errors) != 1 {
+		t.Fatalf("Expected a ParseError with one InvalidClaimsError but got %v", err)
+	}
+
+	var rerr *RuleError
+	if !errors.As(err, &rerr) || rerr.Rule.String() != `Equals("tenant", "acme")` {
+		t.Errorf("Expected the tenant rule to fail but got %v", err)
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule([]byte(`{"all": [
+		{"equals": {"claim": "iss", "value": "https://issuer.example.com"}},
+		{"contains": {"claim": "scope", "value": "read"}},
+		{"oneOf": {"claim": "level", "values": [1, 2, 3]}},
+		{"matches": {"claim": "sub", "pattern": "^user-[0-9]+$"}},
+		{"exists": ["sub", "realm_access.roles"]},
+		{"any": [
+			{"equals": {"claim": "admin", "value": true}},
+			{"contains": {"claim": "realm_access.roles", "value": "admin"}}
+		]}
+	]}`))
+
+	if err != nil {
+		t.Fatalf("Error parsing the rule: %v", err)
+	}
+
+	if err := rule.Validate(ruleClaims); err != nil {
+		t.Errorf("%v failed: %v", rule, err)
+	}
+
+	if s := rule.String(); s != `All(Equals("iss", "https://issuer.example.com"), Contains("scope", "read"), OneOf("level", 1, 2, 3), Matches("sub", "^user-[0-9]+$"), Exists("sub", "realm_access.roles"), Any(Equals("admin", true), Contains("realm_access.roles", "admin")))` {
+		t.Errorf("Unexpected rule description %v", s)
+	}
+
+	rule, _ = ParseRule([]byte(`{"equals": {"claim": "tenant", "value": "globex"}}`))
+
+	if err := rule.Validate(ruleClaims); err == nil {
+		t.Errorf("%v passed", rule)
+	}
+
+	// configuration decoded elsewhere, such as from YAML
+	rule, err = RuleFromConfig(map[string]interface{}{
+		"oneOf": map[string]interface{}{"claim": "tenant", "values": []interface{}{"acme", "globex"}},
+	})
+
+	if err != nil {
+		t.Fatalf("Error building the rule: %v", err)
+	}
+
+	if err := rule.Validate(ruleClaims); err != nil {
+		t.Errorf("%v failed: %v", rule, err)
+	}
+
+	for _, config := range []string{
+		`[]`,
+		`{}`,
+		`{"equals": {"claim": "iss", "value": "a"}, "exists": ["sub"]}`,
+		`{"unknown": {"claim": "iss", "value": "a"}}`,
+		`{"equals": {"claim": "iss"}}`,
+		`{"equals": {"value": "a"}}`,
+		`{"equals": {"claim": "", "value": "a"}}`,
+		`{"equals": {"claim": "iss", "value": "a", "extra": 1}}`,
+		`{"equals": {"claim": "iss", "value": "a", "value": "b"}}`,
+		`{"oneOf": {"claim": "tenant", "values": "acme"}}`,
+		`{"matches": {"claim": "sub", "pattern": "("}}`,
+		`{"matches": {"claim": "sub", "pattern": 1}}`,
+		`{"exists": "sub"}`,
+		`{"exists": ["sub", 1]}`,
+		`{"all": {"exists": ["sub"]}}`,
+		`{"any": [{"exists": ["sub"]}, {"nope": []}]}`,
+		`{"exists": ["sub"]} {}`,
+	} {
+		if _, err := ParseRule([]byte(config)); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("Parsing %s, expected ErrInvalidRule but got %v", config, err)
+		}
+	}
+}
+
+func TestMatchesPattern(t *testing.T) {
+	rule, err := MatchesPattern("sub", `^user-\d+$`)
+
+	if err != nil {
+		t.Fatalf("Error building the rule: %v", err)
+	}
+
+	if err := rule.Validate(ruleClaims); err != nil {
+		t.Errorf("%v failed: %v", rule, err)
+	}
+
+	if _, err := MatchesPattern("sub", "("); !errors.Is(err, ErrInvalidRule) {
+		t.Errorf("Expected ErrInvalidRule but got %v", err)
+	}
+
+	// a nil expression fails rather than panicking
+	rule = Matches("sub", nil)
+
+	if err := rule.Validate(ruleClaims); err == nil || err.Error() != `Matches("sub", nil): the expression is nil` {
+		t.Errorf("Expected a nil expression to fail but got %v", err)
+	}
+}
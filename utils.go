@@ -3,17 +3,117 @@ package jwt
 import (
 	"encoding/base64"
 	"strings"
+	"sync"
 )
 
+// maxPooledBuffer is the capacity above which buffers aren't returned to the
+// pool so that one unusually large token doesn't pin memory
+const maxPooledBuffer = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 1024)
+		return &b
+	},
+}
+
+// getBuffer returns an empty pooled buffer with a capacity of at least n
+func getBuffer(n int) *[]byte {
+	bp := bufferPool.Get().(*[]byte)
+
+	if cap(*bp) < n {
+		*bp = make([]byte, 0, n)
+	}
+
+	return bp
+}
+
+func putBuffer(bp *[]byte) {
+	if cap(*bp) > maxPooledBuffer {
+		return
+	}
+
+	*bp = (*bp)[:0]
+	bufferPool.Put(bp)
+}
+
+// strictEncoding rejects encodings with non-zero trailing bits
+var strictEncoding = base64.RawURLEncoding.Strict()
+
 func encode(data []byte) string {
-	return strings.TrimRight(base64.URLEncoding.EncodeToString(data), "=")
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// appendEncode appends the base64url encoding of src to dst
+func appendEncode(dst []byte, src []byte) []byte {
+	n := len(dst)
+	l := base64.RawURLEncoding.EncodedLen(len(src))
+
+	if cap(dst)-n < l {
+		grown := make([]byte, n, 2*cap(dst)+l)
+		copy(grown, dst)
+		dst = grown
+	}
+
+	dst = dst[:n+l]
+	base64.RawURLEncoding.Encode(dst[n:], src)
+
+	return dst
 }
 
 func decode(data string) ([]byte, error) {
-	// add padding that was removed by encoder
-	if l := len(data) % 4; l > 0 {
-		data += strings.Repeat("=", 4-l)
+	// tolerate padding that wasn't removed by the encoder
+	pad := len(data) - len(strings.TrimRight(data, "="))
+
+	return base64.RawURLEncoding.DecodeString(data[:unpaddedLen(len(data), pad)])
+}
+
+// unpaddedLen returns the length of an encoded segment of length n, ending
+// with pad "=" characters, once its padding is removed. Only as much padding
+// as completes the final block is removed, so that a segment has no more
+// encodings than it would with the padded alphabet.
+func unpaddedLen(n int, pad int) int {
+	switch (n - pad) % 4 {
+	case 2:
+		if pad <= 2 {
+			return n - pad
+		}
+	case 3:
+		if pad <= 1 {
+			return n - pad
+		}
+	}
+
+	return n
+}
+
+// decodeSegment decodes src into dst, which must be large enough to hold the
+// decoded data, and returns the decoded slice of dst
+func decodeSegment(dst []byte, src []byte) ([]byte, error) {
+	// tolerate padding that wasn't removed by the encoder
+	pad := 0
+	for pad < len(src) && src[len(src)-1-pad] == '=' {
+		pad++
+	}
+
+	src = src[:unpaddedLen(len(src), pad)]
+
+	n, err := base64.RawURLEncoding.Decode(dst, src)
+
+	return dst[:n], err
+}
+
+// decodeSegmentStrict is like decodeSegment but only accepts the canonical
+// unpadded encoding
+func decodeSegmentStrict(dst []byte, src []byte) ([]byte, error) {
+	// the decoder skips line breaks, even in strict mode
+	for i, c := range src {
+		if c == '\r' || c == '\n' {
+			return nil, base64.CorruptInputError(i)
+		}
 	}
 
-	return base64.URLEncoding.DecodeString(data)
+	n, err := strictEncoding.Decode(dst, src)
+
+	return dst[:n], err
 }
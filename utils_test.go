@@ -0,0 +1,77 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+var decodeTests = []struct {
+	in      string
+	out     []byte
+	lenient bool
+	strict  bool
+}{
+	{"", []byte{}, true, true},
+	{"Zg", []byte("f"), true, true},
+	{"Zm8", []byte("fo"), true, true},
+	{"Zm9v", []byte("foo"), true, true},
+	{"_-8", []byte{0xff, 0xef}, true, true},
+
+	// padding
+	{"Zg==", []byte("f"), true, false},
+	{"Zm8=", []byte("fo"), true, false},
+	{"Zg=", []byte("f"), true, false},
+
+	// non-zero trailing bits
+	{"Zh", []byte("f"), true, false},
+	{"Zm9", []byte("fo"), true, false},
+
+	// line breaks are skipped by encoding/base64
+	{"Zm\n9v", []byte("foo"), true, false},
+	{"Zm9v\r\n", []byte("foo"), true, false},
+
+	// never valid
+	{"Z", nil, false, false},
+	{" Zm9v", nil, false, false},
+	{"Zm 9v", nil, false, false},
+	{"Zm9v\t", nil, false, false},
+	{"/+8", nil, false, false},
+	{"Zg==Zg", nil, false, false},
+
+	// more padding than the final block allows
+	{"=", nil, false, false},
+	{"Zg===", nil, false, false},
+	{"Zm8==", nil, false, false},
+	{"Zm9v=", nil, false, false},
+	{"Zm9v====", nil, false, false},
+}
+
+func TestDecodeSegment(t *testing.T) {
+	for _, test := range decodeTests {
+		for _, strict := range []bool{false, true} {
+			decodeFunc, valid := decodeSegment, test.lenient
+			if strict {
+				decodeFunc, valid = decodeSegmentStrict, test.strict
+			}
+
+			dst := make([]byte, base64.RawURLEncoding.DecodedLen(len(test.in)))
+			out, err := decodeFunc(dst, []byte(test.in))
+
+			if valid && err != nil {
+				t.Errorf("[strict=%v] Error decoding %q: %v", strict, test.in, err)
+			} else if valid && !bytes.Equal(out, test.out) {
+				t.Errorf("[strict=%v] Decoding %q, expected %v but got %v", strict, test.in, test.out, out)
+			} else if !valid && err == nil {
+				t.Errorf("[strict=%v] Decoded invalid input %q", strict, test.in)
+			}
+		}
+
+		// decode is used for keys and is as lenient as decodeSegment
+		if out, err := decode(test.in); test.lenient && (err != nil || !bytes.Equal(out, test.out)) {
+			t.Errorf("Decoding %q, expected %v but got %v (%v)", test.in, test.out, out, err)
+		} else if !test.lenient && err == nil {
+			t.Errorf("Decoded invalid input %q", test.in)
+		}
+	}
+}